@@ -3,7 +3,7 @@ module github.com/ibrokethecloud/ec2-operator
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.34.12
+	github.com/aws/aws-sdk-go v1.42.30
 	github.com/go-logr/logr v0.1.0
 	github.com/onsi/ginkgo v1.11.0
 	github.com/onsi/gomega v1.8.1
@@ -11,4 +11,5 @@ require (
 	k8s.io/apimachinery v0.17.2
 	k8s.io/client-go v0.17.2
 	sigs.k8s.io/controller-runtime v0.5.0
+	sigs.k8s.io/yaml v1.1.0
 )
@@ -0,0 +1,58 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// CloudInit describes the cloud-init configuration of an instance. It is rendered by the
+// operator into a MIME multipart userdata document.
+type CloudInit struct {
+	Users []CloudInitUser `json:"users,omitempty"`
+	// Keys authorized for the default user of the image
+	SSHAuthorizedKeys []string        `json:"sshAuthorizedKeys,omitempty"`
+	Packages          []string        `json:"packages,omitempty"`
+	WriteFiles        []CloudInitFile `json:"writeFiles,omitempty"`
+	// Commands run on first boot, each passed to the shell
+	RunCmd []string `json:"runcmd,omitempty"`
+	// Additional parts appended to the document, such as shell scripts or boothooks
+	ExtraParts []CloudInitPart `json:"extraParts,omitempty"`
+}
+
+// CloudInitUser is a user created by cloud-init
+type CloudInitUser struct {
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+	// Sudo rule for the user, such as ALL=(ALL) NOPASSWD:ALL
+	Sudo              string   `json:"sudo,omitempty"`
+	Shell             string   `json:"shell,omitempty"`
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+}
+
+// CloudInitFile is a file written by cloud-init
+type CloudInitFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Octal file mode, such as 0644
+	Permissions string `json:"permissions,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// CloudInitPart is an additional part of the cloud-init MIME document
+type CloudInitPart struct {
+	// MIME type of the part, such as text/x-shellscript
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename,omitempty"`
+	Content     string `json:"content"`
+}
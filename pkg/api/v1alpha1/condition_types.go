@@ -0,0 +1,114 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported on the status of the ec2 resources
+const (
+	// ConditionReady is True when the EC2 resource has reached the state described by the spec
+	ConditionReady = "Ready"
+	// ConditionProvisioned is True once the EC2 resource has been created
+	ConditionProvisioned = "Provisioned"
+	// ConditionTagsSynced is True when the tags of the EC2 resource match the spec
+	ConditionTagsSynced = "TagsSynced"
+	// ConditionPublicIPAssigned is True when a requested public IP has been assigned to the instance
+	ConditionPublicIPAssigned = "PublicIPAssigned"
+	// ConditionDegraded is True when the EC2 resource is in a failed or unexpected state
+	ConditionDegraded = "Degraded"
+	// ConditionDrifted is True when the live EC2 resource no longer matches the spec
+	ConditionDrifted = "Drifted"
+	// ConditionUserDataStale is True when the user data resolved for the instance no
+	// longer matches the user data it was launched with
+	ConditionUserDataStale = "UserDataStale"
+	// ConditionAttached is True when a volume is attached to the instance in its spec
+	ConditionAttached = "Attached"
+	// ConditionModificationFailed is True when the modification of a volume towards its
+	// spec was rejected or failed. It is only retried once the spec changes.
+	ConditionModificationFailed = "ModificationFailed"
+	// ConditionProgressing is True while an InstanceSet is rolling out its template or
+	// has completed the rollout, and False when the rollout has been halted
+	ConditionProgressing = "Progressing"
+)
+
+// Condition describes one aspect of the observed state of a resource. It follows the
+// layout of metav1.Condition, which is not available in this version of apimachinery,
+// so that tools such as kubectl wait and GitOps health checks can consume it.
+type Condition struct {
+	Type string `json:"type"`
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+	// Generation of the resource the condition was computed for
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string      `json:"reason,omitempty"`
+	Message            string      `json:"message,omitempty"`
+}
+
+// SetCondition adds or updates the condition of the same type. The transition time is
+// only changed when the status of the condition changes.
+func SetCondition(conditions *[]Condition, condition Condition) {
+	for i := range *conditions {
+		existing := &(*conditions)[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status != condition.Status || existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+		existing.Status = condition.Status
+		existing.ObservedGeneration = condition.ObservedGeneration
+		existing.Reason = condition.Reason
+		existing.Message = condition.Message
+		return
+	}
+
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	*conditions = append(*conditions, condition)
+}
+
+// RemoveCondition removes the condition of the given type if it is set
+func RemoveCondition(conditions *[]Condition, conditionType string) {
+	result := []Condition{}
+	for _, condition := range *conditions {
+		if condition.Type != conditionType {
+			result = append(result, condition)
+		}
+	}
+	*conditions = result
+}
+
+// IsConditionTrue is true when the condition of the given type is set and True
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	condition := FindCondition(conditions, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// FindCondition returns the condition of the given type, or nil if it is not set
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
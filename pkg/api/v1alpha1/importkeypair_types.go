@@ -30,15 +30,29 @@ type ImportKeyPairSpec struct {
 	TagSpecifications []Tags `json:"tagSpecification,omitempty"`
 	Secret            string `json:"credentialSecret"` // K8S secret containing the account creds //
 	Region            string `json:"region"`
+	// What happens to the EC2 keypair when the object is deleted, defaults to Delete
+	// +kubebuilder:validation:Enum=Delete;Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
 }
 
 // ImportKeyPairStatus defines the observed state of ImportKeyPair
 type ImportKeyPairStatus struct {
 	Status    string `json:"status"`
 	KeyPairID string `json:"keyPairID"`
+	// Human readable detail for the current status, usually the last error
+	Message string `json:"message,omitempty"`
+	// Tag keys owned by the operator, tags added outside the operator are left untouched
+	ManagedTags []string `json:"managedTags,omitempty"`
+	// Generation of the spec last handled by the operator
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="KeyPairId",type="string",JSONPath=`.status.keyPairID`
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 
 // ImportKeyPair is the Schema for the importkeypairs API
 type ImportKeyPair struct {
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,19 +26,243 @@ import (
 
 // InstanceSpec defines the desired state of Instance
 type InstanceSpec struct {
-	BlockDeviceMapping string   `json:"blockDeviceMapping,omitempty"`
-	ImageID            string   `json:"imageID"`
-	InstanceType       string   `json:"instanceType"`
-	KeyName            string   `json:"keyname,omitempty"`
-	SecurityGroupIDS   []string `json:"securityGroupIDS,omitempty"`
-	SecurityGroups     []string `json:"securityGroups,omitempty"`
-	SubnetID           string   `json:"subnetID,omitempty"`
-	UserData           string   `json:"userData,omitempty"`
-	IAMInstanceProfile string   `json:"iamInstanceProfile,omitempty"`
-	TagSpecifications  []Tags   `json:"tagSpecification,omitempty"`
-	Secret             string   `json:"credentialSecret"` // K8S secret containing the account creds //
-	PublicIPAddress    bool     `json:"publicIPAddress,omitEmpty"`
-	Region             string   `json:"region"`
+	BlockDeviceMappings []BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
+	// Required unless an existing instance is adopted
+	ImageID string `json:"imageID,omitempty"`
+	// Required unless an existing instance is adopted
+	InstanceType     string   `json:"instanceType,omitempty"`
+	KeyName          string   `json:"keyname,omitempty"`
+	SecurityGroupIDS []string `json:"securityGroupIDS,omitempty"`
+	// Security group names, resolved to ids within the VPC of the subnet
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+	SubnetID       string   `json:"subnetID,omitempty"`
+	// Base64 encoded user data
+	UserData string `json:"userData,omitempty"`
+	// Build the user data from a key of a Secret or ConfigMap instead of userData
+	UserDataFrom *UserDataSource `json:"userDataFrom,omitempty"`
+	// Cloud-init configuration rendered into the user data instead of userData
+	CloudInit *CloudInit `json:"cloudInit,omitempty"`
+	// Render the user data as a Go template before the instance is launched
+	UserDataTemplate   *UserDataTemplate `json:"userDataTemplate,omitempty"`
+	IAMInstanceProfile string            `json:"iamInstanceProfile,omitempty"`
+	TagSpecifications  []Tags            `json:"tagSpecification,omitempty"`
+	Secret             string            `json:"credentialSecret"` // K8S secret containing the account creds //
+	PublicIPAddress    bool              `json:"publicIPAddress,omitEmpty"`
+	Region             string            `json:"region"`
+	// Launch the instance using a non on-demand purchasing option
+	MarketOptions *MarketOptions `json:"marketOptions,omitempty"`
+	// Desired power state of the instance, defaults to Running
+	// +kubebuilder:validation:Enum=Running;Stopped;Hibernated
+	PowerState string `json:"powerState,omitempty"`
+	// Enable hibernation support at launch, required for the Hibernated power state
+	EnableHibernation bool `json:"enableHibernation,omitempty"`
+	// Correct drift between the live instance and the spec found during a resync,
+	// otherwise drift is only reported
+	CorrectDrift bool `json:"correctDrift,omitempty"`
+	// Adopt an existing EC2 instance instead of launching a new one
+	Adopt *AdoptSpec `json:"adopt,omitempty"`
+	// Find the image to launch with DescribeImages instead of specifying imageID
+	ImageSelector *ImageSelector `json:"imageSelector,omitempty"`
+	// Launch from an EC2 launch template, fields set in the spec override the template
+	LaunchTemplate *LaunchTemplateSpec `json:"launchTemplate,omitempty"`
+	// Associate an Elastic IP address with the instance once it is running
+	ElasticIP *ElasticIPSpec `json:"elasticIP,omitempty"`
+	// Number of cores and threads per core, set at launch only
+	CPUOptions *CPUOptions `json:"cpuOptions,omitempty"`
+	// Credit option for CPU usage of burstable instances, applied at launch and to
+	// existing instances
+	CreditSpecification *CreditSpecification `json:"creditSpecification,omitempty"`
+	// Availability zone, placement group, tenancy and host of the instance
+	Placement *Placement `json:"placement,omitempty"`
+	// Instance metadata service options, applied at launch and to existing instances
+	MetadataOptions *MetadataOptions `json:"metadataOptions,omitempty"`
+	// Network interfaces created at launch, replacing the single interface in subnetID.
+	// subnetID and the security groups of the instance are the defaults for each interface.
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+	// What happens to the EC2 instance when the object is deleted, defaults to Delete.
+	// Retain and Stop remove the ownership tags so the instance can be adopted again.
+	// +kubebuilder:validation:Enum=Delete;Retain;Stop
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// UserDataSource references the raw user data in a Secret or ConfigMap in the namespace
+// of the instance. The operator takes care of the encoding.
+type UserDataSource struct {
+	SecretKeyRef    *corev1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// Compress the user data with gzip, allowing larger scripts within the 16KB limit
+	Gzip bool `json:"gzip,omitempty"`
+}
+
+// UserDataTemplate makes the user data a Go template rendered at launch. The template
+// can use .Name, .Namespace, .Labels, .Annotations, .Region, .SubnetID and .InstanceType
+// of the instance, the values of the referenced secrets as .Secrets.<secret>.<key> and
+// the cluster join information as .Cluster.<key>.
+type UserDataTemplate struct {
+	// Secrets in the namespace of the instance available to the template
+	SecretRefs []string `json:"secretRefs,omitempty"`
+	// Secret in the namespace of the instance holding the cluster join information,
+	// such as the server address and join token
+	ClusterSecretRef string `json:"clusterSecretRef,omitempty"`
+}
+
+// AdoptSpec identifies an existing instance to be managed by the operator, either by id
+// or by tags which must match exactly one live instance
+type AdoptSpec struct {
+	InstanceID  string            `json:"instanceID,omitempty"`
+	TagSelector map[string]string `json:"tagSelector,omitempty"`
+}
+
+// Power states that can be requested for an instance
+const (
+	PowerStateRunning    = "Running"
+	PowerStateStopped    = "Stopped"
+	PowerStateHibernated = "Hibernated"
+)
+
+// Deletion policies for the EC2 resources backing an object
+const (
+	DeletionPolicyDelete = "Delete"
+	DeletionPolicyRetain = "Retain"
+	DeletionPolicyStop   = "Stop"
+)
+
+// DisableRestartAnnotation set to "true" on an Instance prevents the operator from
+// stopping and starting a running instance to apply spec changes such as the instance type
+const DisableRestartAnnotation = "ec2.cattle.io/disable-restart"
+
+// MarketOptions describes the purchasing option for the instance
+type MarketOptions struct {
+	// +kubebuilder:validation:Enum=spot
+	MarketType  string       `json:"marketType"`
+	SpotOptions *SpotOptions `json:"spotOptions,omitempty"`
+}
+
+// SpotOptions describes the spot request made for the instance
+type SpotOptions struct {
+	// Maximum hourly price, defaults to the on-demand price
+	MaxPrice string `json:"maxPrice,omitempty"`
+	// +kubebuilder:validation:Enum=hibernate;stop;terminate
+	InstanceInterruptionBehavior string `json:"instanceInterruptionBehavior,omitempty"`
+	// +kubebuilder:validation:Enum=one-time;persistent
+	SpotInstanceType string `json:"spotInstanceType,omitempty"`
+	// Launch a replacement instance when the spot instance is terminated by EC2
+	RelaunchOnTermination bool `json:"relaunchOnTermination,omitempty"`
+}
+
+// BlockDeviceMapping describes an EBS volume attached to the instance at launch
+type BlockDeviceMapping struct {
+	DeviceName string `json:"deviceName"`
+	// Size of the volume in GiB. Defaults to the snapshot size for the root volume
+	VolumeSize int64 `json:"volumeSize,omitempty"`
+	// +kubebuilder:validation:Enum=standard;io1;io2;gp2;gp3;sc1;st1
+	VolumeType string `json:"volumeType,omitempty"`
+	IOPS       int64  `json:"iops,omitempty"`
+	// Throughput in MiB/s, only supported by gp3 volumes
+	Throughput int64  `json:"throughput,omitempty"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+	KMSKeyID   string `json:"kmsKeyID,omitempty"`
+	// Left unset the AWS default for the device is used
+	DeleteOnTermination *bool `json:"deleteOnTermination,omitempty"`
+}
+
+// ImageSelector finds an AMI by its attributes. The image resolved at launch is pinned in
+// the status.
+type ImageSelector struct {
+	// Account ids or aliases such as amazon or self owning the image
+	Owners []string `json:"owners,omitempty"`
+	// Image name, * and ? can be used as wildcards
+	Name string `json:"name,omitempty"`
+	// +kubebuilder:validation:Enum=i386;x86_64;arm64
+	Architecture string            `json:"architecture,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	// Pick the most recently created image when several match, otherwise the selector
+	// has to match exactly one image
+	MostRecent bool `json:"mostRecent,omitempty"`
+}
+
+// LaunchTemplateSpec references an EC2 launch template by id or name
+type LaunchTemplateSpec struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// $Latest, $Default or a version number, defaults to $Default
+	Version string `json:"version,omitempty"`
+}
+
+// ElasticIPSpec describes the Elastic IP address associated with the instance
+type ElasticIPSpec struct {
+	// Existing allocation to associate with the instance, a new address is allocated
+	// when unset. Only addresses allocated by the operator are released.
+	AllocationID string `json:"allocationID,omitempty"`
+}
+
+// CPUOptions sets the CPU topology of the instance
+type CPUOptions struct {
+	// +kubebuilder:validation:Minimum=1
+	CoreCount int64 `json:"coreCount"`
+	// Set to 1 to disable multithreading
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2
+	ThreadsPerCore int64 `json:"threadsPerCore,omitempty"`
+}
+
+// CreditSpecification sets the credit option of a burstable (T family) instance
+type CreditSpecification struct {
+	// +kubebuilder:validation:Enum=standard;unlimited
+	CPUCredits string `json:"cpuCredits"`
+}
+
+// Placement controls where the instance is launched
+type Placement struct {
+	// Has to match the availability zone of the subnets of the instance
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// Name of a cluster, spread or partition placement group
+	GroupName string `json:"groupName,omitempty"`
+	// Partition of a partition placement group, EC2 picks one when unset
+	PartitionNumber int64 `json:"partitionNumber,omitempty"`
+	// +kubebuilder:validation:Enum=default;dedicated;host
+	Tenancy string `json:"tenancy,omitempty"`
+	// Dedicated host to launch on, requires host tenancy
+	HostID string `json:"hostID,omitempty"`
+	// Keep the instance on the same dedicated host when it is restarted
+	// +kubebuilder:validation:Enum=default;host
+	Affinity string `json:"affinity,omitempty"`
+}
+
+// MetadataOptions configures the instance metadata service. Options left unset keep the
+// EC2 defaults.
+type MetadataOptions struct {
+	// Set to required to enforce IMDSv2 session tokens
+	// +kubebuilder:validation:Enum=required;optional
+	HTTPTokens string `json:"httpTokens,omitempty"`
+	// Number of network hops the metadata PUT response can travel
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	HTTPPutResponseHopLimit int64 `json:"httpPutResponseHopLimit,omitempty"`
+	// +kubebuilder:validation:Enum=enabled;disabled
+	HTTPEndpoint string `json:"httpEndpoint,omitempty"`
+	// Set to enabled to expose the tags of the instance in the metadata service
+	// +kubebuilder:validation:Enum=enabled;disabled
+	InstanceMetadataTags string `json:"instanceMetadataTags,omitempty"`
+}
+
+// NetworkInterface describes an elastic network interface created with the instance
+type NetworkInterface struct {
+	// Position of the interface on the instance, 0 is the primary interface
+	DeviceIndex int64  `json:"deviceIndex"`
+	SubnetID    string `json:"subnetID,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Primary private IPv4 address, assigned from the subnet when unset
+	PrivateIPAddress string `json:"privateIPAddress,omitempty"`
+	// Number of secondary private IPv4 addresses assigned from the subnet
+	SecondaryPrivateIPAddressCount int64 `json:"secondaryPrivateIPAddressCount,omitempty"`
+	// Number of IPv6 addresses assigned from the subnet
+	IPv6AddressCount int64    `json:"ipv6AddressCount,omitempty"`
+	SecurityGroupIDS []string `json:"securityGroupIDS,omitempty"`
+	// Security group names, resolved to ids within the VPC of the subnet
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+	// Disable for interfaces of NAT or routing instances, defaults to true
+	SourceDestCheck *bool `json:"sourceDestCheck,omitempty"`
 }
 
 type Tags struct {
@@ -51,13 +276,92 @@ type InstanceStatus struct {
 	InstanceID string `json:"instanceID"`
 	PrivateIP  string `json:"privateIP"`
 	PublicIP   string `json:"publicIP"`
+	PrivateDNS string `json:"privateDNS,omitempty"`
+	PublicDNS  string `json:"publicDNS,omitempty"`
+	// EC2 state of the instance, eg. pending, running, stopped
+	InstanceState string `json:"instanceState,omitempty"`
+	// Instance type currently applied to the instance
+	InstanceType          string `json:"instanceType,omitempty"`
+	SpotInstanceRequestID string `json:"spotInstanceRequestID,omitempty"`
+	// Human readable detail for the current status, usually the last error
+	Message string `json:"message,omitempty"`
+	// Security group ids the instance was launched with
+	SecurityGroupIDS []string `json:"securityGroupIDS,omitempty"`
+	// Volumes attached to the instance keyed by device name
+	BlockDevices []BlockDeviceStatus `json:"blockDevices,omitempty"`
+	// Network interfaces attached to the instance
+	NetworkInterfaces []NetworkInterfaceStatus `json:"networkInterfaces,omitempty"`
+	// Elastic IP address associated with the instance
+	ElasticIP *ElasticIPStatus `json:"elasticIP,omitempty"`
+	// CPU topology of the instance
+	CPUOptions *CPUOptions `json:"cpuOptions,omitempty"`
+	// Credit option applied to a burstable instance
+	CPUCredits string `json:"cpuCredits,omitempty"`
+	// Availability zone the instance runs in
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// Partition of the placement group the instance runs in
+	PartitionNumber int64 `json:"partitionNumber,omitempty"`
+	// Metadata service options applied to the instance
+	MetadataOptions *MetadataOptions `json:"metadataOptions,omitempty"`
+	// Image the instance was launched with
+	ImageID string `json:"imageID,omitempty"`
+	// Launch template and resolved version the instance was launched from
+	LaunchTemplate *LaunchTemplateStatus `json:"launchTemplate,omitempty"`
+	// Tag keys owned by the operator, tags added outside the operator are left untouched
+	ManagedTags []string `json:"managedTags,omitempty"`
+	// Last time the status was refreshed from EC2
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Hash of the encoded user data the instance was launched with
+	UserDataHash string `json:"userDataHash,omitempty"`
+	// Adopted is true when the instance was launched outside the operator
+	Adopted bool `json:"adopted,omitempty"`
+	// Number of times a terminated spot instance has been replaced
+	Relaunches int64 `json:"relaunches,omitempty"`
+	// Generation of the spec last handled by the operator
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+// BlockDeviceStatus records the EBS volume backing a device on the instance
+type BlockDeviceStatus struct {
+	DeviceName string `json:"deviceName"`
+	VolumeID   string `json:"volumeID"`
+}
+
+// LaunchTemplateStatus records the launch template version an instance was launched from
+type LaunchTemplateStatus struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// ElasticIPStatus records the Elastic IP address of the instance
+type ElasticIPStatus struct {
+	AllocationID  string `json:"allocationID"`
+	AssociationID string `json:"associationID,omitempty"`
+	PublicIP      string `json:"publicIP,omitempty"`
+	// Allocated is true when the address was allocated by the operator
+	Allocated bool `json:"allocated,omitempty"`
+}
+
+// NetworkInterfaceStatus records a network interface attached to the instance
+type NetworkInterfaceStatus struct {
+	DeviceIndex         int64    `json:"deviceIndex"`
+	NetworkInterfaceID  string   `json:"networkInterfaceID"`
+	SubnetID            string   `json:"subnetID,omitempty"`
+	PrivateIP           string   `json:"privateIP,omitempty"`
+	SecondaryPrivateIPs []string `json:"secondaryPrivateIPs,omitempty"`
+	IPv6Addresses       []string `json:"ipv6Addresses,omitempty"`
+	SourceDestCheck     bool     `json:"sourceDestCheck"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="InstanceId",type="string",JSONPath=`.status.instanceID`
 // +kubebuilder:printcolumn:name="PublicIP",type="string",JSONPath=`.status.publicIP`
 // +kubebuilder:printcolumn:name="PrivateIP",type="string",JSONPath=`.status.privateIP`
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=`.status.instanceState`
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 
 // Instance is the Schema for the instances API
 type Instance struct {
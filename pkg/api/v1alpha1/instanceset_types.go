@@ -0,0 +1,141 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// TemplateHashLabel is set on the instances of an InstanceSet to the hash of the
+// template they were created from
+const TemplateHashLabel = "ec2.cattle.io/template-hash"
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// InstanceSetSpec defines the desired state of InstanceSet
+type InstanceSetSpec struct {
+	// Number of instances, defaults to 1
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Label selector for the instances of the set, it has to match the template labels
+	Selector *metav1.LabelSelector `json:"selector"`
+	// Template the instances of the set are created from
+	Template InstanceTemplateSpec `json:"template"`
+	// Seconds an instance has to be Ready before it counts as available
+	// +kubebuilder:validation:Minimum=0
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// How instances are replaced when the template changes
+	Strategy InstanceSetStrategy `json:"strategy,omitempty"`
+	// Stops rolling out template changes until the set is resumed
+	Paused bool `json:"paused,omitempty"`
+	// Number of old templates kept for rollback, defaults to 10
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+	// Seconds a new instance may take to become Ready before the rollout is halted,
+	// defaults to 600
+	// +kubebuilder:validation:Minimum=0
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// Restores the template of an earlier revision, the field is cleared once the
+	// template has been restored
+	RollbackTo *InstanceSetRollback `json:"rollbackTo,omitempty"`
+}
+
+// InstanceSetStrategy controls the rolling replacement of instances
+type InstanceSetStrategy struct {
+	// Number or percentage of instances created above the desired replicas during a
+	// rollout, defaults to 1
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// Number or percentage of the desired replicas that may be unavailable during a
+	// rollout, defaults to 0 so new instances are Ready before old ones are deleted
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// InstanceSetRollback selects the revision to roll back to
+type InstanceSetRollback struct {
+	// Revision to restore, 0 selects the previous revision
+	// +kubebuilder:validation:Minimum=0
+	Revision int64 `json:"revision,omitempty"`
+}
+
+// InstanceTemplateSpec describes the Instances created by an InstanceSet
+type InstanceTemplateSpec struct {
+	Metadata InstanceTemplateMeta `json:"metadata,omitempty"`
+	Spec     InstanceSpec         `json:"spec"`
+}
+
+// InstanceTemplateMeta holds the labels and annotations of the Instances created from a
+// template
+type InstanceTemplateMeta struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// InstanceSetStatus defines the observed state of InstanceSet
+type InstanceSetStatus struct {
+	// Number of instances owned by the set
+	Replicas int32 `json:"replicas"`
+	// Number of instances with a Ready condition
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Number of instances Ready for at least minReadySeconds
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// Number of instances created from the current template
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// Revision of the current template
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+	// Hash of the current template, as set in the template-hash label of its instances
+	TemplateHash string `json:"templateHash,omitempty"`
+	// Label selector of the instances in string form, used by the scale subresource
+	Selector string `json:"selector,omitempty"`
+	// Human readable detail for the current status, usually the last error
+	Message string `json:"message,omitempty"`
+	// Generation of the spec last handled by the operator
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=`.spec.replicas`
+// +kubebuilder:printcolumn:name="Current",type="integer",JSONPath=`.status.replicas`
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=`.status.readyReplicas`
+// +kubebuilder:printcolumn:name="Up-To-Date",type="integer",JSONPath=`.status.updatedReplicas`
+// +kubebuilder:printcolumn:name="Available",type="integer",JSONPath=`.status.availableReplicas`
+
+// InstanceSet is the Schema for the instancesets API
+type InstanceSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InstanceSetSpec   `json:"spec,omitempty"`
+	Status InstanceSetStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// InstanceSetList contains a list of InstanceSet
+type InstanceSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InstanceSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&InstanceSet{}, &InstanceSetList{})
+}
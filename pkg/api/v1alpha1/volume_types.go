@@ -0,0 +1,123 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// VolumeSpec defines the desired state of Volume
+type VolumeSpec struct {
+	// Defaults to the availability zone of the attached instance
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	// Size of the volume in GiB, defaults to the snapshot size. Can be increased on an
+	// existing volume but not decreased
+	Size int64 `json:"size,omitempty"`
+	// +kubebuilder:validation:Enum=standard;io1;io2;gp2;gp3;sc1;st1
+	VolumeType string `json:"volumeType,omitempty"`
+	IOPS       int64  `json:"iops,omitempty"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+	KMSKeyID   string `json:"kmsKeyID,omitempty"`
+	// Snapshot the volume is created from
+	SnapshotID string `json:"snapshotID,omitempty"`
+	// Instance the volume is attached to
+	Attachment        *VolumeAttachment `json:"attachment,omitempty"`
+	TagSpecifications []Tags            `json:"tagSpecification,omitempty"`
+	Secret            string            `json:"credentialSecret"` // K8S secret containing the account creds //
+	Region            string            `json:"region"`
+	// What happens to the EBS volume when the object is deleted, defaults to Delete.
+	// The volume is detached first either way.
+	// +kubebuilder:validation:Enum=Delete;Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// VolumeAttachment references the Instance in the namespace of the volume to attach to
+type VolumeAttachment struct {
+	InstanceRef string `json:"instanceRef"`
+	// Device name exposed to the instance, such as /dev/sdf
+	DeviceName string `json:"deviceName"`
+}
+
+// VolumeStatus defines the observed state of Volume
+type VolumeStatus struct {
+	Status   string `json:"status"`
+	VolumeID string `json:"volumeID"`
+	// EBS state of the volume, eg. creating, available, in-use
+	VolumeState      string `json:"volumeState,omitempty"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	Size             int64  `json:"size,omitempty"`
+	VolumeType       string `json:"volumeType,omitempty"`
+	IOPS             int64  `json:"iops,omitempty"`
+	// Instance the volume is currently attached to
+	AttachedInstanceID string `json:"attachedInstanceID,omitempty"`
+	DeviceName         string `json:"deviceName,omitempty"`
+	// EBS state of the attachment, eg. attaching, attached, detaching
+	AttachmentState string `json:"attachmentState,omitempty"`
+	// Latest modification of the volume
+	Modification *VolumeModificationStatus `json:"modification,omitempty"`
+	// Human readable detail for the current status, usually the last error
+	Message string `json:"message,omitempty"`
+	// Tag keys owned by the operator, tags added outside the operator are left untouched
+	ManagedTags []string `json:"managedTags,omitempty"`
+	// Generation of the spec last handled by the operator
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+// VolumeModificationStatus reports the progress of a volume modification
+type VolumeModificationStatus struct {
+	// modifying, optimizing, completed or failed
+	State      string `json:"state"`
+	Progress   int64  `json:"progress,omitempty"`
+	TargetSize int64  `json:"targetSize,omitempty"`
+	TargetType string `json:"targetType,omitempty"`
+	TargetIOPS int64  `json:"targetIOPS,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="VolumeId",type="string",JSONPath=`.status.volumeID`
+// +kubebuilder:printcolumn:name="Size",type="integer",JSONPath=`.status.size`
+// +kubebuilder:printcolumn:name="Instance",type="string",JSONPath=`.status.attachedInstanceID`
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+
+// Volume is the Schema for the volumes API
+type Volume struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeSpec   `json:"spec,omitempty"`
+	Status VolumeStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VolumeList contains a list of Volume
+type VolumeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Volume `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Volume{}, &VolumeList{})
+}
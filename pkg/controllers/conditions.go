@@ -0,0 +1,353 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	corev1 "k8s.io/api/core/v1"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+	"github.com/ibrokethecloud/ec2-operator/pkg/ec2"
+)
+
+// setInstanceConditions derives the Ready, Provisioned, PublicIPAssigned and Degraded
+// conditions from the status of the instance. TagsSynced and Drifted are set when the
+// tags are reconciled and the instance is resynced.
+func setInstanceConditions(instance *ec2v1alpha1.Instance) {
+	status := &instance.Status
+	generation := instance.Generation
+	status.ObservedGeneration = generation
+
+	provisioned := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionProvisioned,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "InstanceLaunched",
+		Message:            fmt.Sprintf("Instance %s launched", status.InstanceID),
+	}
+	switch {
+	case status.Adopted && len(status.InstanceID) > 0:
+		provisioned.Reason = "InstanceAdopted"
+		provisioned.Message = fmt.Sprintf("Instance %s adopted", status.InstanceID)
+	case status.Status == ec2.Error && len(status.InstanceID) == 0:
+		provisioned.Status = corev1.ConditionFalse
+		provisioned.Reason = "LaunchFailed"
+		provisioned.Message = status.Message
+	case len(status.InstanceID) == 0:
+		provisioned.Status = corev1.ConditionFalse
+		provisioned.Reason = "Pending"
+		provisioned.Message = "Instance has not been launched yet"
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, provisioned)
+
+	if instance.Spec.PublicIPAddress || instance.Spec.ElasticIP != nil {
+		publicIP := ec2v1alpha1.Condition{
+			Type:               ec2v1alpha1.ConditionPublicIPAssigned,
+			Status:             corev1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "PublicIPAssigned",
+			Message:            status.PublicIP,
+		}
+		if len(status.PublicIP) == 0 {
+			publicIP.Status = corev1.ConditionFalse
+			publicIP.Reason = "WaitingForPublicIP"
+			publicIP.Message = "Public IP has not been assigned yet"
+		}
+		ec2v1alpha1.SetCondition(&status.Conditions, publicIP)
+	} else {
+		ec2v1alpha1.RemoveCondition(&status.Conditions, ec2v1alpha1.ConditionPublicIPAssigned)
+	}
+
+	degraded := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+	}
+	drifted := ec2v1alpha1.FindCondition(status.Conditions, ec2v1alpha1.ConditionDrifted)
+	switch {
+	case status.Status == ec2.Error:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "Error"
+		degraded.Message = status.Message
+	case status.Status == ec2.SpotInterrupted:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "SpotInterrupted"
+		degraded.Message = status.Message
+	case status.Status == ec2.Terminated:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "InstanceTerminated"
+		degraded.Message = status.Message
+	case drifted != nil && drifted.Status == corev1.ConditionTrue && (!instance.Spec.CorrectDrift || drifted.Reason == "InstanceTerminated"):
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "Drifted"
+		degraded.Message = drifted.Message
+	case len(status.Message) > 0:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "ReconcileFailed"
+		degraded.Message = status.Message
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, degraded)
+
+	ready := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "Reconciling",
+		Message:            fmt.Sprintf("Instance is %s", status.Status),
+	}
+	switch {
+	case degraded.Status == corev1.ConditionTrue:
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	case provisioned.Status != corev1.ConditionTrue:
+		ready.Reason = provisioned.Reason
+		ready.Message = provisioned.Message
+	case !conditionCurrent(status.Conditions, ec2v1alpha1.ConditionTagsSynced, generation):
+		ready.Reason = "TagsNotSynced"
+		ready.Message = "Tags have not been applied for the current spec"
+	case status.Status == ec2.DesiredStatus(*instance):
+		ready.Status = corev1.ConditionTrue
+		ready.Reason = "Ready"
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, ready)
+}
+
+// setKeyPairConditions derives the Ready, Provisioned and Degraded conditions from the
+// status of the keypair. TagsSynced is set when the tags are reconciled.
+func setKeyPairConditions(keypair *ec2v1alpha1.ImportKeyPair) {
+	status := &keypair.Status
+	generation := keypair.Generation
+	status.ObservedGeneration = generation
+
+	provisioned := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionProvisioned,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "KeyPairImported",
+		Message:            fmt.Sprintf("KeyPair %s imported", status.KeyPairID),
+	}
+	if len(status.KeyPairID) == 0 {
+		provisioned.Status = corev1.ConditionFalse
+		provisioned.Reason = "Pending"
+		provisioned.Message = "KeyPair has not been imported yet"
+		if status.Status == ec2.Error {
+			provisioned.Reason = "ImportFailed"
+			provisioned.Message = status.Message
+		}
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, provisioned)
+
+	degraded := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+	}
+	if len(status.Message) > 0 {
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "Error"
+		degraded.Message = status.Message
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, degraded)
+
+	ready := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionReady,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Ready",
+	}
+	switch {
+	case degraded.Status == corev1.ConditionTrue:
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	case provisioned.Status != corev1.ConditionTrue:
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = provisioned.Reason
+		ready.Message = provisioned.Message
+	case !conditionCurrent(status.Conditions, ec2v1alpha1.ConditionTagsSynced, generation):
+		ready.Status = corev1.ConditionFalse
+		ready.Reason = "TagsNotSynced"
+		ready.Message = "Tags have not been applied for the current spec"
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, ready)
+}
+
+// setVolumeConditions derives the Ready, Provisioned, Attached and Degraded conditions
+// from the status of the volume. TagsSynced is set when the tags are reconciled.
+func setVolumeConditions(volume *ec2v1alpha1.Volume) {
+	status := &volume.Status
+	generation := volume.Generation
+	status.ObservedGeneration = generation
+
+	provisioned := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionProvisioned,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "VolumeCreated",
+		Message:            fmt.Sprintf("Volume %s created", status.VolumeID),
+	}
+	switch {
+	case len(status.VolumeID) == 0 && status.Status == ec2.Error:
+		provisioned.Status = corev1.ConditionFalse
+		provisioned.Reason = "CreateFailed"
+		provisioned.Message = status.Message
+	case len(status.VolumeID) == 0 || status.Status == ec2.Creating:
+		provisioned.Status = corev1.ConditionFalse
+		provisioned.Reason = "Pending"
+		provisioned.Message = "Volume has not been created yet"
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, provisioned)
+
+	attached := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionAttached,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Attached",
+		Message:            fmt.Sprintf("Volume attached to %s as %s", status.AttachedInstanceID, status.DeviceName),
+	}
+	if volume.Spec.Attachment != nil {
+		if status.AttachmentState != awsec2.VolumeAttachmentStateAttached {
+			attached.Status = corev1.ConditionFalse
+			attached.Reason = "Pending"
+			attached.Message = fmt.Sprintf("Volume is not attached to instance %s yet", volume.Spec.Attachment.InstanceRef)
+		}
+		ec2v1alpha1.SetCondition(&status.Conditions, attached)
+	} else {
+		ec2v1alpha1.RemoveCondition(&status.Conditions, ec2v1alpha1.ConditionAttached)
+	}
+
+	degraded := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+	}
+	switch {
+	case len(status.Message) > 0:
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "Error"
+		degraded.Message = status.Message
+	case ec2v1alpha1.IsConditionTrue(status.Conditions, ec2v1alpha1.ConditionModificationFailed):
+		failed := ec2v1alpha1.FindCondition(status.Conditions, ec2v1alpha1.ConditionModificationFailed)
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = failed.Reason
+		degraded.Message = failed.Message
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, degraded)
+
+	ready := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "Reconciling",
+		Message:            fmt.Sprintf("Volume is %s", status.Status),
+	}
+	switch {
+	case degraded.Status == corev1.ConditionTrue:
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	case provisioned.Status != corev1.ConditionTrue:
+		ready.Reason = provisioned.Reason
+		ready.Message = provisioned.Message
+	case volume.Spec.Attachment != nil && attached.Status != corev1.ConditionTrue:
+		ready.Reason = "NotAttached"
+		ready.Message = attached.Message
+	case !conditionCurrent(status.Conditions, ec2v1alpha1.ConditionTagsSynced, generation):
+		ready.Reason = "TagsNotSynced"
+		ready.Message = "Tags have not been applied for the current spec"
+	case status.Status == ec2.Provisioned:
+		ready.Status = corev1.ConditionTrue
+		ready.Reason = "Ready"
+		ready.Message = ""
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, ready)
+}
+
+// setInstanceSetConditions derives the Ready, Progressing and Degraded conditions from
+// the replica counts of the set and the state of its rollout
+func setInstanceSetConditions(set *ec2v1alpha1.InstanceSet, rollingUpdate bool, failure string) {
+	status := &set.Status
+	generation := set.Generation
+	status.ObservedGeneration = generation
+
+	degraded := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionDegraded,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+	}
+	if len(status.Message) > 0 {
+		degraded.Status = corev1.ConditionTrue
+		degraded.Reason = "ReconcileFailed"
+		degraded.Message = status.Message
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, degraded)
+
+	progressing := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionProgressing,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "RolloutComplete",
+		Message:            fmt.Sprintf("revision %d has been rolled out", status.CurrentRevision),
+	}
+	switch {
+	case set.Spec.Paused:
+		progressing.Status = corev1.ConditionUnknown
+		progressing.Reason = "RolloutPaused"
+		progressing.Message = "rollout is paused"
+	case rollingUpdate && len(failure) > 0:
+		progressing.Status = corev1.ConditionFalse
+		progressing.Reason = rolloutHalted
+		progressing.Message = failure
+	case rollingUpdate:
+		progressing.Reason = "RollingUpdate"
+		progressing.Message = fmt.Sprintf("%d of %d instances updated to revision %d",
+			status.UpdatedReplicas, instanceSetReplicas(*set), status.CurrentRevision)
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, progressing)
+
+	desired := instanceSetReplicas(*set)
+	ready := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionReady,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "Scaling",
+		Message:            fmt.Sprintf("%d of %d instances available", status.AvailableReplicas, desired),
+	}
+	switch {
+	case degraded.Status == corev1.ConditionTrue:
+		ready.Reason = degraded.Reason
+		ready.Message = degraded.Message
+	case rollingUpdate:
+		ready.Reason = progressing.Reason
+		ready.Message = progressing.Message
+	case status.Replicas == desired && status.AvailableReplicas == desired:
+		ready.Status = corev1.ConditionTrue
+		ready.Reason = "Ready"
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, ready)
+}
+
+// conditionCurrent is true when the condition is True for the current generation of the object
+func conditionCurrent(conditions []ec2v1alpha1.Condition, conditionType string, generation int64) bool {
+	condition := ec2v1alpha1.FindCondition(conditions, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue && condition.ObservedGeneration == generation
+}
@@ -76,29 +76,56 @@ func (r *ImportKeyPairReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 	}
 
 	if keypair.ObjectMeta.DeletionTimestamp.IsZero() {
+		// the finalizer is added before the key is imported, so it is never left behind
+		// when the status update after the import fails
+		if !containsString(keypair.ObjectMeta.Finalizers, keypairFinalizer) {
+			controllerutil.AddFinalizer(&keypair, keypairFinalizer)
+			if err := r.Update(ctx, &keypair); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
 		status := ec2v1alpha1.ImportKeyPairStatus{}
 		// only create if keypair.Status.Status is empty
-		if keypair.Status.Status == "" {
+		if keypair.Status.Status == "" || keypair.Status.Status == ec2.Error {
 			status, err = awsClient.ImportKeyPair(keypair)
+		} else if !conditionCurrent(keypair.Status.Conditions, ec2v1alpha1.ConditionTagsSynced, keypair.Generation) {
+			log.Info("Reconciling tags")
+			status, err = awsClient.ReconcileKeyPairTags(keypair)
+		} else if keypair.Status.ObservedGeneration != keypair.Generation {
+			// record that the latest spec has been handled
+			status = keypair.Status
 		} else {
 			// Ignore otherwise
 			return ctrl.Result{}, nil
 		}
 
 		if err != nil {
-			log.Info("Error during keypair creation")
+			log.Info("Error during keypair reconcile")
+			keypair.Status = status
+			setKeyPairConditions(&keypair)
+			if updateErr := r.Status().Update(ctx, &keypair); updateErr != nil {
+				log.Info("Error updating the keypair status")
+			}
 			return ctrl.Result{}, err
 		}
-		controllerutil.AddFinalizer(&keypair, keypairFinalizer)
 		keypair.Status = status
+		setKeyPairConditions(&keypair)
 
-		if err := r.Update(ctx, &keypair); err != nil {
-			log.Info("Error updating the keypair status and finalizer")
+		if err := r.Status().Update(ctx, &keypair); err != nil {
+			log.Info("Error updating the keypair status")
 			return ctrl.Result{}, err
 		}
 	} else {
+		if !containsString(keypair.ObjectMeta.Finalizers, keypairFinalizer) {
+			return ctrl.Result{}, nil
+		}
 
-		if err := awsClient.DeleteKeyPair(keypair); err != nil {
+		if len(keypair.Status.KeyPairID) == 0 {
+			log.Info("Keypair was never imported")
+		} else if keypair.Spec.DeletionPolicy == ec2v1alpha1.DeletionPolicyRetain {
+			log.Info("Retaining keypair", "keyPairID", keypair.Status.KeyPairID)
+		} else if err := awsClient.DeleteKeyPair(keypair); err != nil {
 			log.Info("Error deleting keypair")
 			return ctrl.Result{}, err
 		}
@@ -19,34 +19,54 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ibrokethecloud/ec2-operator/pkg/ec2"
 
 	corev1 "k8s.io/api/core/v1"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
 )
 
+const (
+	// spotCheckInterval is how often provisioned spot instances are checked for interruptions
+	spotCheckInterval = 1 * time.Minute
+	// powerStateCheckInterval is how often an instance is checked while starting or stopping
+	powerStateCheckInterval = 15 * time.Second
+)
+
 // InstanceReconciler reconciles a Instance object
 type InstanceReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// ResyncInterval is how often provisioned instances are compared against EC2,
+	// zero disables the resync
+	ResyncInterval time.Duration
 }
 
 // +kubebuilder:rbac:groups=ec2.cattle.io,resources=instances,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ec2.cattle.io,resources=instances/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 func (r *InstanceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	instanceFinalizer := "instance.cattle.io"
@@ -79,75 +99,371 @@ func (r *InstanceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 	// Launch a new instance //
 	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		// the finalizer is added before anything is launched, so an instance is never
+		// left behind when the status update after the launch fails
+		if !containsString(instance.ObjectMeta.Finalizers, instanceFinalizer) {
+			controllerutil.AddFinalizer(&instance, instanceFinalizer)
+			if err := r.Update(ctx, &instance); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
 		// Check if instance needs to be launched //
 		instanceStatus := ec2v1alpha1.InstanceStatus{}
 		currentStatus := instance.Status.DeepCopy()
 		switch status := currentStatus.Status; status {
-		case "":
-			log.Info("Creating instance")
-			instanceStatus, err = awsClient.CreateInstance(instance)
+		case "", ec2.Error:
+			if instance.Spec.Adopt != nil {
+				log.Info("Adopting instance")
+				instanceStatus, err = awsClient.AdoptInstance(instance)
+			} else {
+				log.Info("Creating instance")
+				instanceStatus, err = r.launchInstance(ctx, awsClient, instance)
+			}
 		case ec2.WaitForPublicIP:
-			log.Info("Fetching Public IP")
-			instanceStatus, err = awsClient.FetchPublicIP(instance)
+			if instance.Spec.ElasticIP != nil {
+				log.Info("Associating Elastic IP")
+				instanceStatus, err = awsClient.AssociateElasticIP(instance)
+			} else {
+				log.Info("Fetching Public IP")
+				instanceStatus, err = awsClient.FetchPublicIP(instance)
+			}
 		case ec2.WaitForTag:
 			log.Info("Updating Tags")
 			instanceStatus, err = awsClient.UpdateTags(instance)
+		case ec2.Provisioned, ec2.SpotInterrupted, ec2.Starting, ec2.Stopping, ec2.Stopped, ec2.Hibernated, ec2.Resizing:
+			var changed bool
+			instanceStatus, changed, err = r.reconcileProvisioned(ctx, awsClient, instance)
+			if err == nil && !changed {
+				if instance.Status.ObservedGeneration == instance.Generation {
+					return r.requeueResult(instance), nil
+				}
+				// record that the latest spec has been handled
+				instanceStatus = *currentStatus
+			}
 		default:
 			return ctrl.Result{}, nil
 		}
 
 		if err != nil {
-			log.Error(fmt.Errorf("Error during instance creation"), instance.ObjectMeta.Name)
+			log.Error(err, "Error while reconciling instance", "status", currentStatus.Status)
+			if len(instanceStatus.Message) > 0 {
+				instance.Status = instanceStatus
+				setInstanceConditions(&instance)
+				if updateErr := r.Status().Update(ctx, &instance); updateErr != nil {
+					log.Error(fmt.Errorf("Error while updating status of instance"), instance.ObjectMeta.Name)
+				}
+			}
 			return ctrl.Result{}, err
 		}
 
 		instance.Status = instanceStatus
-		controllerutil.AddFinalizer(&instance, instanceFinalizer)
-		if err = r.Update(ctx, &instance); err != nil {
+		setInstanceConditions(&instance)
+		if err = r.Status().Update(ctx, &instance); err != nil {
 			log.Error(fmt.Errorf("Error while updating status of instance"), instance.ObjectMeta.Name)
-			// there is an edge case when update fails after launch an instance //
-			// new status should have been updated to WaitForTag, however due to failure
-			// the re-provisioning logic will re-run when the object falls through the
-			// switch block. So we are going to take the WaitForTag status and delete
-			// the instance
-			if instance.Status.Status == ec2.WaitForTag {
-				_ = awsClient.DeleteInstance(instance)
-			}
+			// if the update fails after an instance was launched the next reconcile
+			// will find it through its client token or ownership tag, and record it
+			// instead of launching a duplicate
 			return ctrl.Result{}, err
 		}
 
 	} else {
-		if containsString(instance.ObjectMeta.Finalizers, instanceFinalizer) {
-			// lets delete the instance //
-			log.Info("Terminating")
-			if err = awsClient.DeleteInstance(instance); err != nil {
+		if !containsString(instance.ObjectMeta.Finalizers, instanceFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		// a launch, such as the replacement of a terminated spot instance, may have
+		// succeeded without its id being recorded, so look it up by its ownership tag
+		if len(instance.Status.InstanceID) == 0 {
+			instanceID, err := awsClient.LaunchedInstanceID(instance)
+			if err != nil {
+				log.Error(err, "Error looking up launched instance so requeueing")
+				return ctrl.Result{}, err
+			}
+			instance.Status.InstanceID = instanceID
+		}
+
+		// lets delete the instance, if one was ever launched //
+		if len(instance.Status.InstanceID) > 0 {
+			log.Info("Removing instance", "deletionPolicy", instance.Spec.DeletionPolicy)
+			removed, err := awsClient.RemoveInstance(instance)
+			if err != nil {
 				log.Error(fmt.Errorf("Error during instance deletion so requeueing"), instance.ObjectMeta.Name)
 				return ctrl.Result{}, err
 			}
+			if !removed {
+				if instance.Status.Status != ec2.Terminating {
+					instance.Status.Status = ec2.Terminating
+					instance.Status.Message = ""
+					setInstanceConditions(&instance)
+					if err := r.Status().Update(ctx, &instance); err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+				return ctrl.Result{RequeueAfter: powerStateCheckInterval}, nil
+			}
+		}
+
+		if err := awsClient.ReleaseElasticIP(instance); err != nil {
+			log.Error(err, "Error releasing Elastic IP so requeueing")
+			return ctrl.Result{}, err
 		}
 
 		controllerutil.RemoveFinalizer(&instance, instanceFinalizer)
 		if err := r.Update(ctx, &instance); err != nil {
 			return ctrl.Result{}, err
 		}
+		return ctrl.Result{}, nil
 	}
 
 	// Requeue object if its not yet completed provisioning
-	// Default flow of object is
-	// 1.Create Instance
-	// 2.Create Tags
-	// 3.Check For public IP if specified
+	return r.requeueResult(instance), nil
+}
+
+// reconcileProvisioned manages an instance once it has been launched: it applies
+// instance type changes, enforces the desired power state, keeps tags and network
+// interfaces in line with the spec, flags stale user data and checks spot instances for
+// interruptions. changed is false when there was nothing to do.
+func (r *InstanceReconciler) reconcileProvisioned(ctx context.Context, awsClient *ec2.AWSClient, instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, changed bool, err error) {
+	if instance.Status.Status != ec2.SpotInterrupted && len(instance.Spec.InstanceType) > 0 && instance.Spec.InstanceType != instance.Status.InstanceType {
+		// changes that can not be applied are reported once, and the remaining checks
+		// such as the spot interruption handling carry on
+		var reason, message string
+		switch {
+		case instance.Spec.MarketOptions != nil:
+			reason = "InstanceTypeUnchangeable"
+			message = fmt.Sprintf("Instance type of spot instance %s can not be changed to %s",
+				instance.Status.InstanceID, instance.Spec.InstanceType)
+		case instance.Status.Status == ec2.Provisioned && len(instance.Status.InstanceType) > 0 && restartDisabled(instance):
+			reason = "RestartDisabled"
+			message = fmt.Sprintf("Instance type change to %s requires a restart which is disabled by the %s annotation",
+				instance.Spec.InstanceType, ec2v1alpha1.DisableRestartAnnotation)
+		default:
+			r.Log.Info("Changing instance type", "instance", instance.Name, "instanceType", instance.Spec.InstanceType)
+			status, err = awsClient.ResizeInstance(instance)
+			return status, true, err
+		}
+		if instance.Status.Message != message {
+			r.Recorder.Event(&instance, corev1.EventTypeWarning, reason, message)
+			status = *instance.Status.DeepCopy()
+			status.Message = message
+			return status, true, nil
+		}
+	}
+
+	// spot instances stopped by EC2 can not be started, so wait for EC2 to resume them
+	if instance.Status.Status != ec2.SpotInterrupted && instance.Status.Status != ec2.DesiredStatus(instance) {
+		r.Log.Info("Reconciling power state", "instance", instance.Name, "powerState", instance.Spec.PowerState)
+		status, err = awsClient.ReconcilePowerState(instance)
+		return status, true, err
+	}
+
+	if !conditionCurrent(instance.Status.Conditions, ec2v1alpha1.ConditionTagsSynced, instance.Generation) {
+		r.Log.Info("Reconciling tags", "instance", instance.Name)
+		status, err = awsClient.ReconcileInstanceTags(instance)
+		return status, true, err
+	}
+
+	if instance.Spec.ElasticIP != nil && (instance.Status.ElasticIP == nil || len(instance.Status.ElasticIP.AssociationID) == 0) {
+		r.Log.Info("Associating Elastic IP", "instance", instance.Name)
+		status, err = awsClient.AssociateElasticIP(instance)
+		return status, true, err
+	}
+
+	if ec2.MetadataOptionsPending(instance) {
+		r.Log.Info("Updating metadata options", "instance", instance.Name)
+		status, err = awsClient.ReconcileMetadataOptions(instance)
+		return status, true, err
+	}
+
+	if ec2.CreditSpecificationPending(instance) {
+		r.Log.Info("Updating credit specification", "instance", instance.Name)
+		status, err = awsClient.ReconcileCreditSpecification(instance)
+		return status, true, err
+	}
+
+	if ec2.SourceDestCheckPending(instance) {
+		r.Log.Info("Updating source/dest check", "instance", instance.Name)
+		status, err = awsClient.ReconcileSourceDestCheck(instance)
+		return status, true, err
+	}
+
+	if len(instance.Status.UserDataHash) > 0 {
+		condition := r.userDataStaleCondition(ctx, instance)
+		previous := ec2v1alpha1.FindCondition(instance.Status.Conditions, ec2v1alpha1.ConditionUserDataStale)
+		if previous == nil || previous.Status != condition.Status || previous.Message != condition.Message {
+			if condition.Status == corev1.ConditionTrue {
+				r.Recorder.Event(&instance, corev1.EventTypeWarning, condition.Reason, condition.Message)
+			}
+			status = *instance.Status.DeepCopy()
+			ec2v1alpha1.SetCondition(&status.Conditions, condition)
+			return status, true, nil
+		}
+	}
+
+	if r.resyncDue(instance) {
+		r.Log.Info("Resyncing instance", "instance", instance.Name)
+		return r.resyncInstance(awsClient, instance)
+	}
+
+	if !isActiveSpotInstance(instance) {
+		return status, false, nil
+	}
+
+	r.Log.Info("Checking spot instance", "instance", instance.Name)
+	status, err = awsClient.CheckSpotInstance(instance)
+	if err != nil {
+		return status, true, err
+	}
+	return r.handleSpotInterruption(instance, status), true, nil
+}
+
+// launchInstance resolves the user data and launches the instance. User data that can
+// not be resolved is reported in the status without calling RunInstances.
+func (r *InstanceReconciler) launchInstance(ctx context.Context, awsClient *ec2.AWSClient, instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	userData, err := r.resolveUserData(ctx, instance)
+	if err != nil {
+		status = *instance.Status.DeepCopy()
+		status.Status = ec2.Error
+		status.Message = err.Error()
+		return status, err
+	}
 
-	if instance.Status.Status != "provisioned" {
-		return ctrl.Result{Requeue: true}, nil
+	status, err = awsClient.CreateInstance(instance, userData)
+	if err == nil {
+		status.UserDataHash = ec2.UserDataHash(userData)
+	}
+	return status, err
+}
+
+// requeueResult decides when the instance needs to be looked at again
+// Default flow of object is
+// 1.Create Instance
+// 2.Create Tags
+// 3.Check For public IP if specified
+// Once provisioned the instance is only requeued while it is changing power state, or
+// periodically to check spot instances for interruptions
+func (r *InstanceReconciler) requeueResult(instance ec2v1alpha1.Instance) ctrl.Result {
+	switch instance.Status.Status {
+	case ec2.Starting, ec2.Stopping, ec2.Resizing:
+		return ctrl.Result{RequeueAfter: powerStateCheckInterval}
+	case ec2.Provisioned, ec2.SpotInterrupted, ec2.Stopped, ec2.Hibernated:
+		requeueAfter := r.ResyncInterval
+		if isActiveSpotInstance(instance) && (requeueAfter == 0 || spotCheckInterval < requeueAfter) {
+			requeueAfter = spotCheckInterval
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}
+	}
+
+	return ctrl.Result{Requeue: true}
+}
+
+// resyncDue is true when the instance has not been compared against EC2 within the
+// resync interval
+func (r *InstanceReconciler) resyncDue(instance ec2v1alpha1.Instance) bool {
+	if r.ResyncInterval == 0 {
+		return false
+	}
+	lastSync := instance.Status.LastSyncTime
+	return lastSync == nil || time.Since(lastSync.Time) >= r.ResyncInterval
+}
+
+// resyncInstance refreshes the status from EC2 and reports drift from the spec in the
+// Drifted condition, correcting it when requested in the spec
+func (r *InstanceReconciler) resyncInstance(awsClient *ec2.AWSClient, instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, changed bool, err error) {
+	status, drift, err := awsClient.SyncInstance(instance, instance.Spec.CorrectDrift)
+	if err != nil {
+		status.Message = err.Error()
+		return status, true, err
+	}
+
+	now := metav1.Now()
+	status.LastSyncTime = &now
+
+	condition := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionDrifted,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             "InSync",
+	}
+	if len(drift) > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "DriftDetected"
+		switch {
+		case status.InstanceState == awsec2.InstanceStateNameShuttingDown || status.InstanceState == awsec2.InstanceStateNameTerminated:
+			condition.Reason = "InstanceTerminated"
+		case instance.Spec.CorrectDrift:
+			condition.Reason = "DriftCorrected"
+		}
+		condition.Message = strings.Join(drift, "; ")
+
+		previous := ec2v1alpha1.FindCondition(instance.Status.Conditions, ec2v1alpha1.ConditionDrifted)
+		if previous == nil || previous.Message != condition.Message {
+			r.Recorder.Event(&instance, corev1.EventTypeWarning, condition.Reason, condition.Message)
+		}
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, condition)
+
+	return status, true, nil
+}
+
+// restartDisabled is true when the instance is annotated to never be restarted by the operator
+func restartDisabled(instance ec2v1alpha1.Instance) bool {
+	return instance.Annotations[ec2v1alpha1.DisableRestartAnnotation] == "true"
+}
+
+// isActiveSpotInstance is true for spot instances that have not been terminated
+func isActiveSpotInstance(instance ec2v1alpha1.Instance) bool {
+	return instance.Spec.MarketOptions != nil && instance.Status.InstanceState != awsec2.InstanceStateNameTerminated
+}
+
+// handleSpotInterruption records events for spot interruptions and resets the status
+// of terminated spot instances that should be relaunched
+func (r *InstanceReconciler) handleSpotInterruption(instance ec2v1alpha1.Instance, status ec2v1alpha1.InstanceStatus) ec2v1alpha1.InstanceStatus {
+	if status.Status != ec2.SpotInterrupted {
+		if instance.Status.Status == ec2.SpotInterrupted {
+			r.Recorder.Event(&instance, corev1.EventTypeNormal, "SpotResumed", "Spot instance is running again")
+		}
+		return status
+	}
+
+	if instance.Status.Status != ec2.SpotInterrupted {
+		r.Recorder.Eventf(&instance, corev1.EventTypeWarning, "SpotInterrupted",
+			"Spot instance %s was interrupted: %s", status.InstanceID, status.Message)
+	}
+
+	spotOptions := instance.Spec.MarketOptions.SpotOptions
+	if status.InstanceState == awsec2.InstanceStateNameTerminated && spotOptions != nil && spotOptions.RelaunchOnTermination {
+		r.Recorder.Eventf(&instance, corev1.EventTypeNormal, "Relaunching",
+			"Launching a replacement for terminated spot instance %s", status.InstanceID)
+		// the replacement is launched with the same image
+		relaunch := ec2v1alpha1.InstanceStatus{
+			Relaunches: instance.Status.Relaunches + 1,
+			ImageID:    instance.Status.ImageID,
+		}
+		// keep the Elastic IP so it is associated with the replacement
+		if eip := instance.Status.ElasticIP; eip != nil {
+			relaunch.ElasticIP = &ec2v1alpha1.ElasticIPStatus{
+				AllocationID: eip.AllocationID,
+				PublicIP:     eip.PublicIP,
+				Allocated:    eip.Allocated,
+			}
+		}
+		return relaunch
 	}
 
-	return ctrl.Result{}, nil
+	return status
 }
 
 func (r *InstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ec2v1alpha1.Instance{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.instancesForUserData),
+		}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.instancesForUserData),
+		}).
 		Complete(r)
 }
 
@@ -0,0 +1,382 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// InstanceSetReconciler reconciles a InstanceSet object
+type InstanceSetReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=ec2.cattle.io,resources=instancesets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ec2.cattle.io,resources=instancesets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+
+func (r *InstanceSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("instanceset", req.NamespacedName)
+
+	var set ec2v1alpha1.InstanceSet
+	if err := r.Get(ctx, req.NamespacedName, &set); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch instanceset")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// owned instances are removed by the garbage collector
+	if !set.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if set.Spec.RollbackTo != nil {
+		return ctrl.Result{}, r.rollback(ctx, set)
+	}
+
+	selector, err := instanceSetSelector(set)
+	if err != nil {
+		return r.updateStatus(ctx, set, nil, err)
+	}
+
+	instances, err := r.ownedInstances(ctx, set, selector)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	hash, err := templateHash(set.Spec.Template)
+	if err != nil {
+		return r.updateStatus(ctx, set, instances, err)
+	}
+	set.Status.TemplateHash = hash
+
+	if err := r.labelInstances(ctx, set, instances); err != nil {
+		return r.updateStatus(ctx, set, instances, err)
+	}
+
+	revision, err := r.syncRevisions(ctx, set, instances)
+	if err != nil {
+		return r.updateStatus(ctx, set, instances, err)
+	}
+	set.Status.CurrentRevision = revision
+
+	err = r.scale(ctx, set, instances)
+	return r.updateStatus(ctx, set, instances, err)
+}
+
+// scale creates and deletes the instances planned for the set, see planRollout.
+// Instances added to old templates while a rollout is paused or halted are created from
+// the newest template still in use.
+func (r *InstanceSetReconciler) scale(ctx context.Context, set ec2v1alpha1.InstanceSet, instances []ec2v1alpha1.Instance) error {
+	updated, old := splitInstances(set, activeInstances(instances))
+	plan, err := planRollout(set, updated, old)
+	if err != nil {
+		return err
+	}
+
+	if plan.createOld > 0 {
+		template, hash, err := r.oldTemplate(ctx, set, old)
+		if err != nil {
+			return err
+		}
+		if template == nil {
+			plan.createUpdated += plan.createOld
+		} else if err := r.createInstances(ctx, set, *template, hash, plan.createOld); err != nil {
+			return err
+		}
+	}
+	if err := r.createInstances(ctx, set, set.Spec.Template, set.Status.TemplateHash, plan.createUpdated); err != nil {
+		return err
+	}
+
+	for i := range plan.delete {
+		if err := r.deleteInstance(ctx, set, &plan.delete[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createInstances creates instances named after the set with the lowest free ordinals.
+// The names are deterministic so that a reconcile working from a cache that has not seen
+// the instances created by the previous one gets AlreadyExists, instead of launching
+// duplicate EC2 instances.
+func (r *InstanceSetReconciler) createInstances(ctx context.Context, set ec2v1alpha1.InstanceSet, template ec2v1alpha1.InstanceTemplateSpec, hash string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	list := &ec2v1alpha1.InstanceList{}
+	if err := r.List(ctx, list, client.InNamespace(set.Namespace)); err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, instance := range list.Items {
+		used[instance.Name] = true
+	}
+
+	for ordinal := 0; count > 0; ordinal++ {
+		name := fmt.Sprintf("%s-%d", set.Name, ordinal)
+		if used[name] {
+			continue
+		}
+		instance, err := r.newInstance(set, template, hash, name)
+		if err != nil {
+			return err
+		}
+		if err := r.Create(ctx, instance); err != nil {
+			if errors.IsAlreadyExists(err) {
+				// the cache is behind, the set is reconciled again once it catches up
+				return nil
+			}
+			return err
+		}
+		r.Recorder.Eventf(&set, corev1.EventTypeNormal, "SuccessfulCreate", "Created instance %s", instance.Name)
+		count--
+	}
+	return nil
+}
+
+func (r *InstanceSetReconciler) deleteInstance(ctx context.Context, set ec2v1alpha1.InstanceSet, instance *ec2v1alpha1.Instance) error {
+	if err := r.Delete(ctx, instance); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	r.Recorder.Eventf(&set, corev1.EventTypeNormal, "SuccessfulDelete", "Deleted instance %s", instance.Name)
+	return nil
+}
+
+// newInstance builds an instance of the set from the template with the given hash
+func (r *InstanceSetReconciler) newInstance(set ec2v1alpha1.InstanceSet, template ec2v1alpha1.InstanceTemplateSpec, hash, name string) (*ec2v1alpha1.Instance, error) {
+	template = *template.DeepCopy()
+	instance := &ec2v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   set.Namespace,
+			Labels:      template.Metadata.Labels,
+			Annotations: template.Metadata.Annotations,
+		},
+		Spec: template.Spec,
+	}
+	if instance.Labels == nil {
+		instance.Labels = map[string]string{}
+	}
+	instance.Labels[ec2v1alpha1.TemplateHashLabel] = hash
+	if err := controllerutil.SetControllerReference(&set, instance, r.Scheme); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// labelInstances sets the template-hash label on instances that match the current
+// template but were created before the label was introduced, so they are not replaced
+func (r *InstanceSetReconciler) labelInstances(ctx context.Context, set ec2v1alpha1.InstanceSet, instances []ec2v1alpha1.Instance) error {
+	for i := range instances {
+		instance := &instances[i]
+		if _, ok := instance.Labels[ec2v1alpha1.TemplateHashLabel]; ok {
+			continue
+		}
+		if !equality.Semantic.DeepEqual(instance.Spec, set.Spec.Template.Spec) {
+			continue
+		}
+		patch := client.MergeFrom(instance.DeepCopy())
+		if instance.Labels == nil {
+			instance.Labels = map[string]string{}
+		}
+		instance.Labels[ec2v1alpha1.TemplateHashLabel] = set.Status.TemplateHash
+		if err := r.Patch(ctx, instance, patch); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownedInstances returns the instances in the namespace of the set that are controlled by it
+func (r *InstanceSetReconciler) ownedInstances(ctx context.Context, set ec2v1alpha1.InstanceSet, selector labels.Selector) ([]ec2v1alpha1.Instance, error) {
+	list := &ec2v1alpha1.InstanceList{}
+	if err := r.List(ctx, list, client.InNamespace(set.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	var instances []ec2v1alpha1.Instance
+	for _, instance := range list.Items {
+		if owner := metav1.GetControllerOf(&instance); owner != nil && owner.UID == set.UID {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// updateStatus records the replica counts of the set and requeues it while instances
+// are waiting to become available or a rollout is in progress
+func (r *InstanceSetReconciler) updateStatus(ctx context.Context, set ec2v1alpha1.InstanceSet, instances []ec2v1alpha1.Instance, reconcileErr error) (ctrl.Result, error) {
+	previous := set.Status.DeepCopy()
+	status := &set.Status
+	status.Replicas, status.ReadyReplicas, status.AvailableReplicas, status.UpdatedReplicas = 0, 0, 0, 0
+	status.Message = ""
+	if set.Spec.Selector != nil {
+		if selector, err := metav1.LabelSelectorAsSelector(set.Spec.Selector); err == nil {
+			status.Selector = selector.String()
+		}
+	}
+
+	var requeueAfter time.Duration
+	minReady := time.Duration(set.Spec.MinReadySeconds) * time.Second
+	active := activeInstances(instances)
+	for _, instance := range active {
+		status.Replicas++
+		if len(status.TemplateHash) > 0 && instance.Labels[ec2v1alpha1.TemplateHashLabel] == status.TemplateHash {
+			status.UpdatedReplicas++
+		}
+		ready := ec2v1alpha1.FindCondition(instance.Status.Conditions, ec2v1alpha1.ConditionReady)
+		if ready == nil || ready.Status != corev1.ConditionTrue {
+			continue
+		}
+		status.ReadyReplicas++
+		if readyFor := time.Since(ready.LastTransitionTime.Time); readyFor >= minReady {
+			status.AvailableReplicas++
+		} else if wait := minReady - readyFor; requeueAfter == 0 || wait < requeueAfter {
+			requeueAfter = wait
+		}
+	}
+	if reconcileErr != nil {
+		status.Message = reconcileErr.Error()
+	}
+
+	var failure string
+	rollingUpdate := len(status.TemplateHash) > 0 && status.UpdatedReplicas < status.Replicas
+	if rollingUpdate {
+		updated, _ := splitInstances(set, active)
+		failure = rolloutFailure(set, updated)
+		// the progress deadline of new instances is only noticed on a resync
+		if requeueAfter == 0 || requeueAfter > rolloutResync {
+			requeueAfter = rolloutResync
+		}
+	}
+
+	halted := ec2v1alpha1.FindCondition(status.Conditions, ec2v1alpha1.ConditionProgressing)
+	wasHalted := halted != nil && halted.Reason == rolloutHalted
+	setInstanceSetConditions(&set, rollingUpdate, failure)
+	if len(failure) > 0 && rollingUpdate && !set.Spec.Paused && !wasHalted {
+		r.Recorder.Event(&set, corev1.EventTypeWarning, rolloutHalted, failure)
+	}
+
+	if !equality.Semantic.DeepEqual(previous, status) {
+		if err := r.Status().Update(ctx, &set); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, reconcileErr
+}
+
+func (r *InstanceSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ec2v1alpha1.InstanceSet{}).
+		Owns(&ec2v1alpha1.Instance{}).
+		Complete(r)
+}
+
+// instanceSetSelector returns the selector of the set, checking that it matches the
+// labels of the template so created instances are counted as members
+func instanceSetSelector(set ec2v1alpha1.InstanceSet) (labels.Selector, error) {
+	if set.Spec.Selector == nil {
+		return nil, fmt.Errorf("selector is required")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(set.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if selector.Empty() {
+		return nil, fmt.Errorf("selector must not be empty")
+	}
+	if !selector.Matches(labels.Set(set.Spec.Template.Metadata.Labels)) {
+		return nil, fmt.Errorf("selector does not match the template labels")
+	}
+	return selector, nil
+}
+
+// instanceSetReplicas returns the desired number of replicas, defaulting to 1
+func instanceSetReplicas(set ec2v1alpha1.InstanceSet) int32 {
+	if set.Spec.Replicas == nil {
+		return 1
+	}
+	return *set.Spec.Replicas
+}
+
+// splitInstances separates the instances created from the current template from the
+// ones created from earlier templates
+func splitInstances(set ec2v1alpha1.InstanceSet, instances []ec2v1alpha1.Instance) (updated, old []ec2v1alpha1.Instance) {
+	for _, instance := range instances {
+		if instance.Labels[ec2v1alpha1.TemplateHashLabel] == set.Status.TemplateHash {
+			updated = append(updated, instance)
+		} else {
+			old = append(old, instance)
+		}
+	}
+	return updated, old
+}
+
+// sortForDeletion orders instances that are not Ready first, followed by the most
+// recently created ones
+func sortForDeletion(instances []ec2v1alpha1.Instance) {
+	sort.SliceStable(instances, func(i, j int) bool {
+		readyI := ec2v1alpha1.IsConditionTrue(instances[i].Status.Conditions, ec2v1alpha1.ConditionReady)
+		readyJ := ec2v1alpha1.IsConditionTrue(instances[j].Status.Conditions, ec2v1alpha1.ConditionReady)
+		if readyI != readyJ {
+			return !readyI
+		}
+		return instances[j].CreationTimestamp.Before(&instances[i].CreationTimestamp)
+	})
+}
+
+// instanceAvailable is true when the instance has been Ready for at least minReady
+func instanceAvailable(instance ec2v1alpha1.Instance, minReady time.Duration) bool {
+	ready := ec2v1alpha1.FindCondition(instance.Status.Conditions, ec2v1alpha1.ConditionReady)
+	return ready != nil && ready.Status == corev1.ConditionTrue && time.Since(ready.LastTransitionTime.Time) >= minReady
+}
+
+// activeInstances filters out the instances that are being deleted
+func activeInstances(instances []ec2v1alpha1.Instance) (active []ec2v1alpha1.Instance) {
+	for _, instance := range instances {
+		if instance.DeletionTimestamp.IsZero() {
+			active = append(active, instance)
+		}
+	}
+	return active
+}
@@ -0,0 +1,363 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+const (
+	defaultRevisionHistoryLimit    = 10
+	defaultProgressDeadlineSeconds = 600
+	rolloutHalted                  = "RolloutHalted"
+	rolloutResync                  = 30 * time.Second
+)
+
+// templateHash returns a label safe hash of the instance template
+func templateHash(template ec2v1alpha1.InstanceTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())), nil
+}
+
+// rolloutLimits resolves maxSurge and maxUnavailable against the desired replicas. At
+// least one of them is positive so the rollout can make progress.
+func rolloutLimits(set ec2v1alpha1.InstanceSet) (maxSurge int, maxUnavailable int, err error) {
+	desired := int(instanceSetReplicas(set))
+	surge := intstr.FromInt(1)
+	if set.Spec.Strategy.MaxSurge != nil {
+		surge = *set.Spec.Strategy.MaxSurge
+	}
+	unavailable := intstr.FromInt(0)
+	if set.Spec.Strategy.MaxUnavailable != nil {
+		unavailable = *set.Spec.Strategy.MaxUnavailable
+	}
+
+	if maxSurge, err = intstr.GetValueFromIntOrPercent(&surge, desired, true); err != nil {
+		return 0, 0, fmt.Errorf("invalid maxSurge: %v", err)
+	}
+	if maxUnavailable, err = intstr.GetValueFromIntOrPercent(&unavailable, desired, false); err != nil {
+		return 0, 0, fmt.Errorf("invalid maxUnavailable: %v", err)
+	}
+	if maxSurge == 0 && maxUnavailable == 0 {
+		maxUnavailable = 1
+	}
+	return maxSurge, maxUnavailable, nil
+}
+
+// rolloutFailure describes why the rollout has to halt: a new instance is Degraded, or
+// did not become Ready within the progress deadline. It is empty when the rollout can
+// continue.
+func rolloutFailure(set ec2v1alpha1.InstanceSet, updated []ec2v1alpha1.Instance) string {
+	deadline := time.Duration(defaultProgressDeadlineSeconds) * time.Second
+	if set.Spec.ProgressDeadlineSeconds != nil {
+		deadline = time.Duration(*set.Spec.ProgressDeadlineSeconds) * time.Second
+	}
+
+	for _, instance := range updated {
+		if degraded := ec2v1alpha1.FindCondition(instance.Status.Conditions, ec2v1alpha1.ConditionDegraded); degraded != nil && degraded.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("instance %s is degraded: %s", instance.Name, degraded.Message)
+		}
+		if !ec2v1alpha1.IsConditionTrue(instance.Status.Conditions, ec2v1alpha1.ConditionReady) &&
+			time.Since(instance.CreationTimestamp.Time) > deadline {
+			return fmt.Sprintf("instance %s did not become Ready within %s", instance.Name, deadline)
+		}
+	}
+	return ""
+}
+
+// rolloutPlan is one step of scaling or rolling out an InstanceSet
+type rolloutPlan struct {
+	// number of instances to create from the current template
+	createUpdated int
+	// number of instances to create from the newest template of the old instances
+	createOld int
+	// instances to delete
+	delete []ec2v1alpha1.Instance
+}
+
+// planRollout decides which instances to create and delete to reach the desired
+// replicas from the current template. Instances of older templates are replaced within
+// the maxSurge and maxUnavailable limits. While the rollout is paused or halted the
+// replicas are still scaled, in proportion across the current and old templates.
+func planRollout(set ec2v1alpha1.InstanceSet, updated, old []ec2v1alpha1.Instance) (plan rolloutPlan, err error) {
+	desired := int(instanceSetReplicas(set))
+	if len(old) == 0 {
+		if len(updated) < desired {
+			plan.createUpdated = desired - len(updated)
+		} else {
+			plan.delete = deletionCandidates(updated, len(updated)-desired)
+		}
+		return plan, nil
+	}
+
+	maxSurge, maxUnavailable, err := rolloutLimits(set)
+	if err != nil {
+		return plan, err
+	}
+	if set.Spec.Paused || len(rolloutFailure(set, updated)) > 0 {
+		return scaleProportionally(desired, maxSurge, updated, old), nil
+	}
+
+	plan.createUpdated = desired - len(updated)
+	if surge := desired + maxSurge - len(updated) - len(old); surge < plan.createUpdated {
+		plan.createUpdated = surge
+	}
+	if plan.createUpdated < 0 {
+		plan.createUpdated = 0
+	}
+
+	// old instances that are not available can always go, the available ones only as
+	// long as enough instances remain available
+	minReady := time.Duration(set.Spec.MinReadySeconds) * time.Second
+	available := 0
+	for _, instances := range [][]ec2v1alpha1.Instance{updated, old} {
+		for _, instance := range instances {
+			if instanceAvailable(instance, minReady) {
+				available++
+			}
+		}
+	}
+	budget := available - (desired - maxUnavailable)
+	for _, instance := range deletionCandidates(old, len(old)) {
+		if instanceAvailable(instance, minReady) {
+			if budget <= 0 {
+				continue
+			}
+			budget--
+		}
+		plan.delete = append(plan.delete, instance)
+	}
+	return plan, nil
+}
+
+// scaleProportionally brings the instances of a paused or halted rollout back within
+// the desired replicas and maxSurge, keeping the ratio of current and old instances
+func scaleProportionally(desired, maxSurge int, updated, old []ec2v1alpha1.Instance) (plan rolloutPlan) {
+	total := len(updated) + len(old)
+	if total >= desired && total <= desired+maxSurge {
+		return plan
+	}
+
+	updatedTarget, oldTarget := proportionalReplicas(desired, len(updated), len(old))
+	if updatedTarget > len(updated) {
+		plan.createUpdated = updatedTarget - len(updated)
+	} else {
+		plan.delete = append(plan.delete, deletionCandidates(updated, len(updated)-updatedTarget)...)
+	}
+	if oldTarget > len(old) {
+		plan.createOld = oldTarget - len(old)
+	} else {
+		plan.delete = append(plan.delete, deletionCandidates(old, len(old)-oldTarget)...)
+	}
+	return plan
+}
+
+// proportionalReplicas splits the desired replicas across the current and old instances
+// in the ratio of their current counts, with the remainder going to the current ones
+func proportionalReplicas(desired, updated, old int) (updatedTarget, oldTarget int) {
+	total := updated + old
+	if total == 0 {
+		return desired, 0
+	}
+	oldTarget = old * desired / total
+	return desired - oldTarget, oldTarget
+}
+
+// deletionCandidates returns the first count instances in deletion order, without
+// reordering the given slice
+func deletionCandidates(instances []ec2v1alpha1.Instance, count int) []ec2v1alpha1.Instance {
+	if count <= 0 {
+		return nil
+	}
+	sorted := append([]ec2v1alpha1.Instance(nil), instances...)
+	sortForDeletion(sorted)
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
+}
+
+// revisions returns the controller revisions owned by the set, ordered by revision
+func (r *InstanceSetReconciler) revisions(ctx context.Context, set ec2v1alpha1.InstanceSet) ([]appsv1.ControllerRevision, error) {
+	list := &appsv1.ControllerRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(set.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var revisions []appsv1.ControllerRevision
+	for _, revision := range list.Items {
+		if owner := metav1.GetControllerOf(&revision); owner != nil && owner.UID == set.UID {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// syncRevisions records the current template as the latest revision of the set and
+// prunes old revisions beyond the history limit that no instance is created from. It
+// returns the number of the current revision.
+func (r *InstanceSetReconciler) syncRevisions(ctx context.Context, set ec2v1alpha1.InstanceSet, instances []ec2v1alpha1.Instance) (int64, error) {
+	revisions, err := r.revisions(ctx, set)
+	if err != nil {
+		return 0, err
+	}
+
+	hash := set.Status.TemplateHash
+	var current *appsv1.ControllerRevision
+	var latest int64
+	for i := range revisions {
+		if revisions[i].Labels[ec2v1alpha1.TemplateHashLabel] == hash {
+			current = &revisions[i]
+		}
+		if revisions[i].Revision > latest {
+			latest = revisions[i].Revision
+		}
+	}
+
+	switch {
+	case current == nil:
+		data, err := json.Marshal(set.Spec.Template)
+		if err != nil {
+			return 0, err
+		}
+		current = &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s", set.Name, hash),
+				Namespace: set.Namespace,
+				Labels:    map[string]string{ec2v1alpha1.TemplateHashLabel: hash},
+			},
+			Data:     runtime.RawExtension{Raw: data},
+			Revision: latest + 1,
+		}
+		if err := controllerutil.SetControllerReference(&set, current, r.Scheme); err != nil {
+			return 0, err
+		}
+		if err := r.Create(ctx, current); err != nil {
+			return 0, err
+		}
+	case current.Revision < latest:
+		// a template that was used before, such as after a rollback, becomes the latest
+		current.Revision = latest + 1
+		if err := r.Update(ctx, current); err != nil {
+			return 0, err
+		}
+	}
+
+	limit := defaultRevisionHistoryLimit
+	if set.Spec.RevisionHistoryLimit != nil {
+		limit = int(*set.Spec.RevisionHistoryLimit)
+	}
+	inUse := map[string]bool{}
+	for _, instance := range instances {
+		inUse[instance.Labels[ec2v1alpha1.TemplateHashLabel]] = true
+	}
+	var prunable []appsv1.ControllerRevision
+	for _, revision := range revisions {
+		if revision.Name != current.Name && !inUse[revision.Labels[ec2v1alpha1.TemplateHashLabel]] {
+			prunable = append(prunable, revision)
+		}
+	}
+	for i := 0; i < len(prunable)-limit; i++ {
+		if err := r.Delete(ctx, &prunable[i]); client.IgnoreNotFound(err) != nil {
+			return 0, err
+		}
+	}
+	return current.Revision, nil
+}
+
+// oldTemplate returns the template and hash of the newest revision that old instances
+// were created from, or nil when none of their revisions is recorded
+func (r *InstanceSetReconciler) oldTemplate(ctx context.Context, set ec2v1alpha1.InstanceSet, old []ec2v1alpha1.Instance) (*ec2v1alpha1.InstanceTemplateSpec, string, error) {
+	revisions, err := r.revisions(ctx, set)
+	if err != nil {
+		return nil, "", err
+	}
+
+	inUse := map[string]bool{}
+	for _, instance := range old {
+		inUse[instance.Labels[ec2v1alpha1.TemplateHashLabel]] = true
+	}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		hash := revisions[i].Labels[ec2v1alpha1.TemplateHashLabel]
+		if !inUse[hash] {
+			continue
+		}
+		template := &ec2v1alpha1.InstanceTemplateSpec{}
+		if err := json.Unmarshal(revisions[i].Data.Raw, template); err != nil {
+			return nil, "", err
+		}
+		return template, hash, nil
+	}
+	return nil, "", nil
+}
+
+// rollback restores the template of the revision selected by rollbackTo, or the
+// revision before the current one when it is 0, and clears rollbackTo
+func (r *InstanceSetReconciler) rollback(ctx context.Context, set ec2v1alpha1.InstanceSet) error {
+	revisions, err := r.revisions(ctx, set)
+	if err != nil {
+		return err
+	}
+
+	var target *appsv1.ControllerRevision
+	want := set.Spec.RollbackTo.Revision
+	for i := range revisions {
+		if want == 0 && revisions[i].Revision < set.Status.CurrentRevision ||
+			want != 0 && revisions[i].Revision == want {
+			target = &revisions[i]
+		}
+	}
+
+	set.Spec.RollbackTo = nil
+	if target == nil {
+		r.Recorder.Eventf(&set, corev1.EventTypeWarning, "RollbackRevisionNotFound",
+			"Unable to find revision %d to roll back to", want)
+		return r.Update(ctx, &set)
+	}
+
+	var template ec2v1alpha1.InstanceTemplateSpec
+	if err := json.Unmarshal(target.Data.Raw, &template); err != nil {
+		return err
+	}
+	set.Spec.Template = template
+	r.Recorder.Eventf(&set, corev1.EventTypeNormal, "RolledBack", "Rolled back to revision %d", target.Revision)
+	return r.Update(ctx, &set)
+}
@@ -0,0 +1,312 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+const currentHash = "current"
+
+// testInstance returns an instance of the template with the given hash, created age ago
+// and Ready since then when ready is set
+func testInstance(name, hash string, ready bool, age time.Duration) ec2v1alpha1.Instance {
+	created := metav1.NewTime(time.Now().Add(-age))
+	instance := ec2v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: created,
+			Labels:            map[string]string{ec2v1alpha1.TemplateHashLabel: hash},
+		},
+	}
+	if ready {
+		instance.Status.Conditions = []ec2v1alpha1.Condition{{
+			Type:               ec2v1alpha1.ConditionReady,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: created,
+		}}
+	}
+	return instance
+}
+
+func testSet(replicas int32, modify func(*ec2v1alpha1.InstanceSet)) ec2v1alpha1.InstanceSet {
+	set := ec2v1alpha1.InstanceSet{}
+	set.Spec.Replicas = &replicas
+	set.Status.TemplateHash = currentHash
+	if modify != nil {
+		modify(&set)
+	}
+	return set
+}
+
+func intOrString(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
+
+func TestRolloutLimits(t *testing.T) {
+	for name, test := range map[string]struct {
+		set            ec2v1alpha1.InstanceSet
+		maxSurge       int
+		maxUnavailable int
+		err            bool
+	}{
+		"defaults": {
+			set:      testSet(3, nil),
+			maxSurge: 1,
+		},
+		"percentages": {
+			set: testSet(10, func(set *ec2v1alpha1.InstanceSet) {
+				set.Spec.Strategy.MaxSurge = intOrString(intstr.FromString("25%"))
+				set.Spec.Strategy.MaxUnavailable = intOrString(intstr.FromString("25%"))
+			}),
+			maxSurge:       3,
+			maxUnavailable: 2,
+		},
+		"both zero": {
+			set: testSet(3, func(set *ec2v1alpha1.InstanceSet) {
+				set.Spec.Strategy.MaxSurge = intOrString(intstr.FromInt(0))
+			}),
+			maxUnavailable: 1,
+		},
+		"invalid": {
+			set: testSet(3, func(set *ec2v1alpha1.InstanceSet) {
+				set.Spec.Strategy.MaxSurge = intOrString(intstr.FromString("one"))
+			}),
+			err: true,
+		},
+	} {
+		maxSurge, maxUnavailable, err := rolloutLimits(test.set)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if maxSurge != test.maxSurge || maxUnavailable != test.maxUnavailable {
+			t.Errorf("%s: expected maxSurge %d and maxUnavailable %d, got %d and %d",
+				name, test.maxSurge, test.maxUnavailable, maxSurge, maxUnavailable)
+		}
+	}
+}
+
+func TestRolloutFailure(t *testing.T) {
+	degraded := testInstance("degraded", currentHash, false, time.Minute)
+	degraded.Status.Conditions = []ec2v1alpha1.Condition{{
+		Type:    ec2v1alpha1.ConditionDegraded,
+		Status:  corev1.ConditionTrue,
+		Message: "launch failed",
+	}}
+
+	for name, test := range map[string]struct {
+		updated []ec2v1alpha1.Instance
+		failed  bool
+	}{
+		"ready":             {updated: []ec2v1alpha1.Instance{testInstance("a", currentHash, true, time.Hour)}},
+		"within deadline":   {updated: []ec2v1alpha1.Instance{testInstance("a", currentHash, false, time.Minute)}},
+		"deadline exceeded": {updated: []ec2v1alpha1.Instance{testInstance("a", currentHash, false, time.Hour)}, failed: true},
+		"degraded":          {updated: []ec2v1alpha1.Instance{degraded}, failed: true},
+	} {
+		failure := rolloutFailure(testSet(1, nil), test.updated)
+		if failed := len(failure) > 0; failed != test.failed {
+			t.Errorf("%s: expected failed to be %v, got %q", name, test.failed, failure)
+		}
+	}
+}
+
+func TestSortForDeletion(t *testing.T) {
+	instances := []ec2v1alpha1.Instance{
+		testInstance("ready-old", currentHash, true, time.Hour),
+		testInstance("ready-new", currentHash, true, time.Minute),
+		testInstance("not-ready", currentHash, false, 2*time.Hour),
+	}
+	sortForDeletion(instances)
+
+	expected := []string{"not-ready", "ready-new", "ready-old"}
+	if names := instanceNames(instances); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected deletion order %v, got %v", expected, names)
+	}
+}
+
+func TestProportionalReplicas(t *testing.T) {
+	for _, test := range []struct {
+		desired, updated, old    int
+		updatedTarget, oldTarget int
+	}{
+		{desired: 3, updated: 0, old: 0, updatedTarget: 3, oldTarget: 0},
+		{desired: 8, updated: 1, old: 3, updatedTarget: 2, oldTarget: 6},
+		{desired: 2, updated: 1, old: 3, updatedTarget: 1, oldTarget: 1},
+		{desired: 5, updated: 2, old: 2, updatedTarget: 3, oldTarget: 2},
+	} {
+		updatedTarget, oldTarget := proportionalReplicas(test.desired, test.updated, test.old)
+		if updatedTarget != test.updatedTarget || oldTarget != test.oldTarget {
+			t.Errorf("%d replicas over %d updated and %d old: expected %d and %d, got %d and %d",
+				test.desired, test.updated, test.old, test.updatedTarget, test.oldTarget, updatedTarget, oldTarget)
+		}
+	}
+}
+
+func TestPlanRollout(t *testing.T) {
+	paused := func(set *ec2v1alpha1.InstanceSet) { set.Spec.Paused = true }
+	degraded := testInstance("new-0", currentHash, false, time.Minute)
+	degraded.Status.Conditions = []ec2v1alpha1.Condition{{Type: ec2v1alpha1.ConditionDegraded, Status: corev1.ConditionTrue}}
+
+	for name, test := range map[string]struct {
+		set           ec2v1alpha1.InstanceSet
+		updated       []ec2v1alpha1.Instance
+		old           []ec2v1alpha1.Instance
+		createUpdated int
+		createOld     int
+		deleted       []string
+	}{
+		"scale up": {
+			set:           testSet(3, nil),
+			updated:       []ec2v1alpha1.Instance{testInstance("new-0", currentHash, true, time.Hour)},
+			createUpdated: 2,
+		},
+		"scale down deletes not ready then newest": {
+			set: testSet(1, nil),
+			updated: []ec2v1alpha1.Instance{
+				testInstance("new-0", currentHash, true, time.Hour),
+				testInstance("new-1", currentHash, true, time.Minute),
+				testInstance("new-2", currentHash, false, 2*time.Hour),
+			},
+			deleted: []string{"new-2", "new-1"},
+		},
+		"surge before deleting": {
+			set: testSet(3, nil),
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, time.Hour),
+				testInstance("old-1", "old", true, time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+			createUpdated: 1,
+		},
+		"wait for new instance to be ready": {
+			set:     testSet(3, nil),
+			updated: []ec2v1alpha1.Instance{testInstance("new-0", currentHash, false, time.Minute)},
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, time.Hour),
+				testInstance("old-1", "old", true, time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+		},
+		"delete old once new is available": {
+			set:     testSet(3, nil),
+			updated: []ec2v1alpha1.Instance{testInstance("new-0", currentHash, true, time.Minute)},
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, 3*time.Hour),
+				testInstance("old-1", "old", true, 2*time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+			deleted: []string{"old-2"},
+		},
+		"minReadySeconds delays deletion": {
+			set:     testSet(3, func(set *ec2v1alpha1.InstanceSet) { set.Spec.MinReadySeconds = 600 }),
+			updated: []ec2v1alpha1.Instance{testInstance("new-0", currentHash, true, time.Minute)},
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, time.Hour),
+				testInstance("old-1", "old", true, time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+		},
+		"old instances that are not ready are deleted": {
+			set: testSet(3, nil),
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, time.Hour),
+				testInstance("old-1", "old", true, time.Hour),
+				testInstance("old-2", "old", false, time.Hour),
+			},
+			createUpdated: 1,
+			deleted:       []string{"old-2"},
+		},
+		"maxUnavailable deletes before creating": {
+			set: testSet(4, func(set *ec2v1alpha1.InstanceSet) {
+				set.Spec.Strategy.MaxSurge = intOrString(intstr.FromInt(0))
+				set.Spec.Strategy.MaxUnavailable = intOrString(intstr.FromInt(2))
+			}),
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, 4*time.Hour),
+				testInstance("old-1", "old", true, 3*time.Hour),
+				testInstance("old-2", "old", true, 2*time.Hour),
+				testInstance("old-3", "old", true, time.Hour),
+			},
+			deleted: []string{"old-3", "old-2"},
+		},
+		"paused within surge": {
+			set:     testSet(3, paused),
+			updated: []ec2v1alpha1.Instance{testInstance("new-0", currentHash, true, time.Hour)},
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, time.Hour),
+				testInstance("old-1", "old", true, time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+		},
+		"paused scale up is proportional": {
+			set:     testSet(8, paused),
+			updated: []ec2v1alpha1.Instance{testInstance("new-0", currentHash, true, time.Hour)},
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, time.Hour),
+				testInstance("old-1", "old", true, time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+			createUpdated: 1,
+			createOld:     3,
+		},
+		"halted scale down is proportional": {
+			set:     testSet(2, nil),
+			updated: []ec2v1alpha1.Instance{degraded},
+			old: []ec2v1alpha1.Instance{
+				testInstance("old-0", "old", true, 3*time.Hour),
+				testInstance("old-1", "old", true, 2*time.Hour),
+				testInstance("old-2", "old", true, time.Hour),
+			},
+			deleted: []string{"old-2", "old-1"},
+		},
+	} {
+		plan, err := planRollout(test.set, test.updated, test.old)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if plan.createUpdated != test.createUpdated || plan.createOld != test.createOld {
+			t.Errorf("%s: expected to create %d updated and %d old instances, got %d and %d",
+				name, test.createUpdated, test.createOld, plan.createUpdated, plan.createOld)
+		}
+		if deleted := instanceNames(plan.delete); !reflect.DeepEqual(deleted, test.deleted) {
+			t.Errorf("%s: expected to delete %v, got %v", name, test.deleted, deleted)
+		}
+	}
+}
+
+func instanceNames(instances []ec2v1alpha1.Instance) (names []string) {
+	for _, instance := range instances {
+		names = append(names, instance.Name)
+	}
+	return names
+}
@@ -0,0 +1,222 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+	"github.com/ibrokethecloud/ec2-operator/pkg/ec2"
+)
+
+// resolveUserData returns the base64 encoded user data to launch the instance with,
+// either inline from the spec, rendered from the cloud-init configuration or read from
+// the referenced Secret or ConfigMap key, and rendered as a template when requested
+func (r *InstanceReconciler) resolveUserData(ctx context.Context, instance ec2v1alpha1.Instance) (string, error) {
+	sources := 0
+	for _, set := range []bool{len(instance.Spec.UserData) > 0, instance.Spec.UserDataFrom != nil, instance.Spec.CloudInit != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", fmt.Errorf("Only one of userData, userDataFrom and cloudInit can be specified")
+	}
+
+	if instance.Spec.UserDataTemplate == nil && instance.Spec.UserDataFrom == nil && instance.Spec.CloudInit == nil {
+		return instance.Spec.UserData, nil
+	}
+
+	data, err := r.rawUserData(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+
+	if instance.Spec.UserDataTemplate != nil {
+		templateContext, err := r.userDataContext(ctx, instance)
+		if err != nil {
+			return "", err
+		}
+		if data, err = ec2.RenderUserDataTemplate(data, templateContext); err != nil {
+			return "", err
+		}
+	}
+
+	return ec2.EncodeUserData(data, instance.Spec.UserDataFrom != nil && instance.Spec.UserDataFrom.Gzip)
+}
+
+// rawUserData returns the user data of the instance before it is rendered and encoded
+func (r *InstanceReconciler) rawUserData(ctx context.Context, instance ec2v1alpha1.Instance) ([]byte, error) {
+	if instance.Spec.CloudInit != nil {
+		return ec2.RenderCloudInit(*instance.Spec.CloudInit)
+	}
+
+	source := instance.Spec.UserDataFrom
+	if source == nil {
+		data, err := base64.StdEncoding.DecodeString(instance.Spec.UserData)
+		if err != nil {
+			return nil, fmt.Errorf("userData is not base64 encoded: %v", err)
+		}
+		return data, nil
+	}
+
+	switch {
+	case source.SecretKeyRef != nil:
+		secret := &corev1.Secret{}
+		name := types.NamespacedName{Namespace: instance.Namespace, Name: source.SecretKeyRef.Name}
+		if err := r.Get(ctx, name, secret); err != nil {
+			return nil, fmt.Errorf("Unable to fetch user data secret %s: %v", name.Name, err)
+		}
+		value, ok := secret.Data[source.SecretKeyRef.Key]
+		if !ok {
+			return nil, fmt.Errorf("Key %s not found in user data secret %s", source.SecretKeyRef.Key, name.Name)
+		}
+		return value, nil
+	case source.ConfigMapKeyRef != nil:
+		configMap := &corev1.ConfigMap{}
+		name := types.NamespacedName{Namespace: instance.Namespace, Name: source.ConfigMapKeyRef.Name}
+		if err := r.Get(ctx, name, configMap); err != nil {
+			return nil, fmt.Errorf("Unable to fetch user data configmap %s: %v", name.Name, err)
+		}
+		value, ok := configMap.Data[source.ConfigMapKeyRef.Key]
+		if !ok {
+			return nil, fmt.Errorf("Key %s not found in user data configmap %s", source.ConfigMapKeyRef.Key, name.Name)
+		}
+		return []byte(value), nil
+	}
+
+	return nil, fmt.Errorf("userDataFrom needs a secretKeyRef or configMapKeyRef")
+}
+
+// userDataContext collects the values available to the user data template
+func (r *InstanceReconciler) userDataContext(ctx context.Context, instance ec2v1alpha1.Instance) (ec2.UserDataContext, error) {
+	templateContext := ec2.UserDataContext{
+		Name:         instance.Name,
+		Namespace:    instance.Namespace,
+		Labels:       instance.Labels,
+		Annotations:  instance.Annotations,
+		Region:       instance.Spec.Region,
+		SubnetID:     instance.Spec.SubnetID,
+		InstanceType: instance.Spec.InstanceType,
+		Secrets:      map[string]map[string]string{},
+	}
+
+	spec := instance.Spec.UserDataTemplate
+	for _, name := range spec.SecretRefs {
+		values, err := r.secretValues(ctx, instance.Namespace, name)
+		if err != nil {
+			return templateContext, err
+		}
+		templateContext.Secrets[name] = values
+	}
+
+	if len(spec.ClusterSecretRef) > 0 {
+		values, err := r.secretValues(ctx, instance.Namespace, spec.ClusterSecretRef)
+		if err != nil {
+			return templateContext, err
+		}
+		templateContext.Cluster = values
+	}
+
+	return templateContext, nil
+}
+
+// secretValues returns the decoded values of a secret in the namespace
+func (r *InstanceReconciler) secretValues(ctx context.Context, namespace, name string) (map[string]string, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("Unable to fetch user data template secret %s: %v", name, err)
+	}
+
+	values := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		values[key] = string(value)
+	}
+	return values, nil
+}
+
+// userDataStaleCondition compares the user data currently resolved for the instance
+// with the user data it was launched with. User data only takes effect at launch, so
+// the instance is flagged rather than replaced.
+func (r *InstanceReconciler) userDataStaleCondition(ctx context.Context, instance ec2v1alpha1.Instance) ec2v1alpha1.Condition {
+	condition := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionUserDataStale,
+		Status:             corev1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             "UpToDate",
+	}
+
+	userData, err := r.resolveUserData(ctx, instance)
+	switch {
+	case err != nil:
+		condition.Status = corev1.ConditionUnknown
+		condition.Reason = "UserDataUnavailable"
+		condition.Message = err.Error()
+	case ec2.UserDataHash(userData) != instance.Status.UserDataHash:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "UserDataChanged"
+		condition.Message = "User data has changed since the instance was launched and will only apply to a new instance"
+	}
+	return condition
+}
+
+// instancesForUserData maps a Secret or ConfigMap to the instances in its namespace
+// that take their user data from it
+func (r *InstanceReconciler) instancesForUserData(object handler.MapObject) []reconcile.Request {
+	instances := &ec2v1alpha1.InstanceList{}
+	if err := r.List(context.Background(), instances, client.InNamespace(object.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list instances for user data source", "name", object.Meta.GetName())
+		return nil
+	}
+
+	_, isSecret := object.Object.(*corev1.Secret)
+	var requests []reconcile.Request
+	for _, instance := range instances.Items {
+		if referencesUserDataSource(instance, isSecret, object.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// referencesUserDataSource is true when the user data of the instance is built from the
+// named Secret or ConfigMap
+func referencesUserDataSource(instance ec2v1alpha1.Instance, isSecret bool, name string) bool {
+	if source := instance.Spec.UserDataFrom; source != nil {
+		if isSecret && source.SecretKeyRef != nil && source.SecretKeyRef.Name == name {
+			return true
+		}
+		if !isSecret && source.ConfigMapKeyRef != nil && source.ConfigMapKeyRef.Name == name {
+			return true
+		}
+	}
+
+	if template := instance.Spec.UserDataTemplate; template != nil && isSecret {
+		return template.ClusterSecretRef == name || containsString(template.SecretRefs, name)
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+	"github.com/ibrokethecloud/ec2-operator/pkg/ec2"
+)
+
+// volumeCheckInterval is how often a volume is checked while it is changing
+const volumeCheckInterval = 15 * time.Second
+
+// VolumeReconciler reconciles a Volume object
+type VolumeReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=ec2.cattle.io,resources=volumes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ec2.cattle.io,resources=volumes/status,verbs=get;update;patch
+
+func (r *VolumeReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	volumeFinalizer := "volume.cattle.io"
+	ctx := context.Background()
+	log := r.Log.WithValues("volume", req.NamespacedName)
+
+	var volume ec2v1alpha1.Volume
+	if err := r.Get(ctx, req.NamespacedName, &volume); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "unable to fetch volume")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// check if the k8s secret exists before processing item //
+	secret, ok, err := r.secretExists(ctx, volume)
+	if !ok {
+		log.Error(fmt.Errorf("unable to fetch secret"), volume.ObjectMeta.Name)
+		// Want to requeue as secret may popup later
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, err
+	}
+
+	awsClient, err := ec2.NewAWSClient(*secret, volume.Spec.Region)
+	if err != nil {
+		log.Info("Error creating AWS Client")
+		return ctrl.Result{}, err
+	}
+
+	if !volume.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.removeVolume(ctx, awsClient, volume, volumeFinalizer)
+	}
+
+	if !containsString(volume.ObjectMeta.Finalizers, volumeFinalizer) {
+		controllerutil.AddFinalizer(&volume, volumeFinalizer)
+		if err := r.Update(ctx, &volume); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	var status ec2v1alpha1.VolumeStatus
+	if len(volume.Status.VolumeID) == 0 {
+		var availabilityZone string
+		availabilityZone, err = r.availabilityZone(ctx, volume)
+		if err == nil {
+			log.Info("Creating volume", "availabilityZone", availabilityZone)
+			status, err = awsClient.CreateVolume(volume, availabilityZone)
+		} else {
+			status = *volume.Status.DeepCopy()
+			status.Status = ec2.Error
+			status.Message = err.Error()
+		}
+	} else {
+		status, err = r.reconcileVolume(ctx, awsClient, volume)
+	}
+
+	previous := volume.Status.DeepCopy()
+	volume.Status = status
+	setVolumeConditions(&volume)
+	if err != nil {
+		log.Error(err, "Error while reconciling volume")
+	}
+	if !equality.Semantic.DeepEqual(previous, &volume.Status) {
+		if updateErr := r.Status().Update(ctx, &volume); updateErr != nil {
+			log.Error(updateErr, "Error while updating status of volume")
+			return ctrl.Result{}, updateErr
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if volume.Status.Status != ec2.Provisioned || !ec2v1alpha1.IsConditionTrue(volume.Status.Conditions, ec2v1alpha1.ConditionReady) {
+		return ctrl.Result{RequeueAfter: volumeCheckInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileVolume refreshes a created volume and makes one change towards the spec:
+// resizing the volume, moving its attachment or updating its tags
+func (r *VolumeReconciler) reconcileVolume(ctx context.Context, awsClient *ec2.AWSClient, volume ec2v1alpha1.Volume) (status ec2v1alpha1.VolumeStatus, err error) {
+	status, err = awsClient.RefreshVolume(volume)
+	if err != nil {
+		return status, err
+	}
+	volume.Status = status
+	if status.Status == ec2.Creating || status.Status == ec2.Attaching || status.Status == ec2.Detaching {
+		return status, nil
+	}
+
+	if !ec2.VolumeModificationPending(volume) {
+		ec2v1alpha1.RemoveCondition(&status.Conditions, ec2v1alpha1.ConditionModificationFailed)
+	} else if !ec2.VolumeModifying(status) {
+		// a modification that was rejected or failed is reported once, and only retried
+		// when the spec changes, so the attachment and tags are still reconciled
+		failed := ec2v1alpha1.FindCondition(status.Conditions, ec2v1alpha1.ConditionModificationFailed)
+		switch {
+		case ec2.VolumeModificationFailed(volume.Spec, status):
+			setModificationFailed(&status, volume.Generation, "ModificationFailed", status.Modification.Message)
+		case failed == nil || failed.Status != corev1.ConditionTrue || failed.ObservedGeneration != volume.Generation:
+			r.Log.Info("Modifying volume", "volume", volume.Name)
+			modified, err := awsClient.ModifyVolume(volume)
+			if err == nil {
+				ec2v1alpha1.RemoveCondition(&modified.Conditions, ec2v1alpha1.ConditionModificationFailed)
+				return modified, nil
+			}
+			if !ec2.IsModificationRejected(err) {
+				return modified, err
+			}
+			setModificationFailed(&status, volume.Generation, "ModificationRejected", err.Error())
+		}
+		volume.Status = status
+	}
+
+	instanceID, err := r.attachmentInstanceID(ctx, volume)
+	if err != nil {
+		status.Message = err.Error()
+		return status, nil
+	}
+	switch {
+	case len(status.AttachedInstanceID) > 0 && status.AttachedInstanceID != instanceID:
+		r.Log.Info("Detaching volume", "volume", volume.Name, "instanceID", status.AttachedInstanceID)
+		return awsClient.DetachVolume(volume)
+	case len(status.AttachedInstanceID) == 0 && len(instanceID) > 0:
+		r.Log.Info("Attaching volume", "volume", volume.Name, "instanceID", instanceID)
+		return awsClient.AttachVolume(volume, instanceID)
+	}
+
+	if !conditionCurrent(status.Conditions, ec2v1alpha1.ConditionTagsSynced, volume.Generation) {
+		r.Log.Info("Reconciling tags", "volume", volume.Name)
+		return awsClient.ReconcileVolumeTags(volume)
+	}
+	return status, nil
+}
+
+// setModificationFailed records a modification of the volume that can not be applied
+// for the current generation of the spec
+func setModificationFailed(status *ec2v1alpha1.VolumeStatus, generation int64, reason, message string) {
+	ec2v1alpha1.SetCondition(&status.Conditions, ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionModificationFailed,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// removeVolume detaches the volume, waits for the detachment to complete and deletes
+// the volume unless it is retained, before removing the finalizer
+func (r *VolumeReconciler) removeVolume(ctx context.Context, awsClient *ec2.AWSClient, volume ec2v1alpha1.Volume, finalizer string) (ctrl.Result, error) {
+	if !containsString(volume.ObjectMeta.Finalizers, finalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if len(volume.Status.VolumeID) > 0 {
+		status, err := awsClient.RefreshVolume(volume)
+		if err != nil && !ec2.IsVolumeNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if err == nil {
+			volume.Status = status
+			if len(status.AttachedInstanceID) > 0 {
+				if status.AttachmentState != awsec2.VolumeAttachmentStateDetaching {
+					r.Log.Info("Detaching volume", "volume", volume.Name, "instanceID", status.AttachedInstanceID)
+					if volume.Status, err = awsClient.DetachVolume(volume); err != nil {
+						return ctrl.Result{}, err
+					}
+					if err := r.Status().Update(ctx, &volume); err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+				return ctrl.Result{RequeueAfter: volumeCheckInterval}, nil
+			}
+
+			if volume.Spec.DeletionPolicy == ec2v1alpha1.DeletionPolicyRetain {
+				r.Log.Info("Retaining volume", "volumeID", volume.Status.VolumeID)
+			} else if err := awsClient.DeleteVolume(volume); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	controllerutil.RemoveFinalizer(&volume, finalizer)
+	if err := r.Update(ctx, &volume); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// availabilityZone returns the availability zone to create the volume in, which is the
+// zone of the attached instance unless set in the spec
+func (r *VolumeReconciler) availabilityZone(ctx context.Context, volume ec2v1alpha1.Volume) (string, error) {
+	if len(volume.Spec.AvailabilityZone) > 0 || volume.Spec.Attachment == nil {
+		return volume.Spec.AvailabilityZone, nil
+	}
+
+	instance := &ec2v1alpha1.Instance{}
+	name := types.NamespacedName{Namespace: volume.Namespace, Name: volume.Spec.Attachment.InstanceRef}
+	if err := r.Get(ctx, name, instance); err != nil {
+		return "", fmt.Errorf("Unable to fetch instance %s: %v", name.Name, err)
+	}
+	if len(instance.Status.AvailabilityZone) == 0 {
+		return "", fmt.Errorf("Waiting for instance %s to be launched to find its availability zone", name.Name)
+	}
+	return instance.Status.AvailabilityZone, nil
+}
+
+// attachmentInstanceID returns the id of the EC2 instance the volume should be attached
+// to, which is empty when the volume should be detached
+func (r *VolumeReconciler) attachmentInstanceID(ctx context.Context, volume ec2v1alpha1.Volume) (string, error) {
+	if volume.Spec.Attachment == nil {
+		return "", nil
+	}
+
+	instance := &ec2v1alpha1.Instance{}
+	name := types.NamespacedName{Namespace: volume.Namespace, Name: volume.Spec.Attachment.InstanceRef}
+	if err := r.Get(ctx, name, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return volume.Status.AttachedInstanceID, fmt.Errorf("Instance %s not found", name.Name)
+		}
+		return volume.Status.AttachedInstanceID, err
+	}
+	if len(instance.Status.InstanceID) == 0 || !instance.DeletionTimestamp.IsZero() {
+		return volume.Status.AttachedInstanceID, fmt.Errorf("Waiting for instance %s to be provisioned", name.Name)
+	}
+	return instance.Status.InstanceID, nil
+}
+
+// volumesForInstance maps an Instance to the volumes in its namespace attached to it
+func (r *VolumeReconciler) volumesForInstance(object handler.MapObject) []reconcile.Request {
+	volumes := &ec2v1alpha1.VolumeList{}
+	if err := r.List(context.Background(), volumes, client.InNamespace(object.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list volumes for instance", "instance", object.Meta.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, volume := range volumes.Items {
+		if volume.Spec.Attachment != nil && volume.Spec.Attachment.InstanceRef == object.Meta.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: volume.Namespace, Name: volume.Name},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *VolumeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ec2v1alpha1.Volume{}).
+		Watches(&source.Kind{Type: &ec2v1alpha1.Instance{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.volumesForInstance),
+		}).
+		Complete(r)
+}
+
+func (r *VolumeReconciler) secretExists(ctx context.Context, volume ec2v1alpha1.Volume) (secret *corev1.Secret, ok bool, err error) {
+	if len(volume.Spec.Secret) == 0 {
+		return nil, false, fmt.Errorf("No secret specified in VolumeSpec. Will be ignored")
+	}
+	secret = &corev1.Secret{}
+	namespacedSecret := types.NamespacedName{Namespace: volume.Namespace, Name: volume.Spec.Secret}
+	r.Log.Info("Fetching secret: ", "secret", namespacedSecret)
+	err = r.Get(ctx, namespacedSecret, secret)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return secret, true, nil
+}
@@ -0,0 +1,106 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// AdoptInstance takes over the management of the existing instance referenced in
+// spec.adopt. The instance is tagged with the ownership tags of the object and then
+// follows the same flow as a launched instance. RunInstances is never called.
+func (a *AWSClient) AdoptInstance(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	status.Relaunches = instance.Status.Relaunches
+
+	// an earlier adoption may have tagged the instance before the status was recorded
+	ec2Instance, err := a.findLaunchedInstance(instance, ClientToken(instance))
+	if err == nil && ec2Instance == nil {
+		ec2Instance, err = a.findAdoptableInstance(instance.Spec.Adopt)
+	}
+	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+
+	for _, tag := range ec2Instance.Tags {
+		if aws.StringValue(tag.Key) == OwnerUIDTag && aws.StringValue(tag.Value) != string(instance.UID) {
+			err = fmt.Errorf("Instance %s is already owned by %s", aws.StringValue(ec2Instance.InstanceId), aws.StringValue(tag.Value))
+			status.Status = Error
+			status.Message = err.Error()
+			return status, err
+		}
+	}
+
+	_, err = a.svc.CreateTags(&awsec2.CreateTagsInput{
+		Resources: []*string{ec2Instance.InstanceId},
+		Tags:      ownershipTags(&instance),
+	})
+	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+
+	status = launchedStatus(instance, status, ec2Instance)
+	status.Adopted = true
+	return status, nil
+}
+
+// findAdoptableInstance returns the single live instance matching the instance id or tag
+// selector of the adopt spec
+func (a *AWSClient) findAdoptableInstance(adopt *ec2v1alpha1.AdoptSpec) (*awsec2.Instance, error) {
+	input := &awsec2.DescribeInstancesInput{
+		Filters: []*awsec2.Filter{
+			{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{
+				awsec2.InstanceStateNamePending,
+				awsec2.InstanceStateNameRunning,
+				awsec2.InstanceStateNameStopping,
+				awsec2.InstanceStateNameStopped,
+			})},
+		},
+	}
+
+	switch {
+	case len(adopt.InstanceID) > 0:
+		input.InstanceIds = aws.StringSlice([]string{adopt.InstanceID})
+	case len(adopt.TagSelector) > 0:
+		for key, value := range adopt.TagSelector {
+			input.Filters = append(input.Filters, &awsec2.Filter{
+				Name:   aws.String("tag:" + key),
+				Values: aws.StringSlice([]string{value}),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("adopt requires an instanceID or tagSelector")
+	}
+
+	var matches []*awsec2.Instance
+	err := a.svc.DescribeInstancesPages(input, func(output *awsec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range output.Reservations {
+			matches = append(matches, reservation.Instances...)
+		}
+		return true
+	})
+	if isNotFound(err) {
+		return nil, fmt.Errorf("No instance found to adopt with id %s", adopt.InstanceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("No live instance found to adopt")
+	case 1:
+		return matches[0], nil
+	default:
+		ids := []string{}
+		for _, match := range matches {
+			ids = append(ids, aws.StringValue(match.InstanceId))
+		}
+		return nil, fmt.Errorf("Adopt selector matches %d instances: %v", len(matches), ids)
+	}
+}
@@ -0,0 +1,127 @@
+package ec2
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// cloudInitBoundary separates the parts of the rendered document. It is fixed so the
+// same configuration always renders to the same user data.
+const cloudInitBoundary = "MIMEBOUNDARY-ec2-operator"
+
+// cloudConfig is the subset of the cloud-config format the operator renders
+type cloudConfig struct {
+	Users             []cloudConfigUser `json:"users,omitempty"`
+	SSHAuthorizedKeys []string          `json:"ssh_authorized_keys,omitempty"`
+	Packages          []string          `json:"packages,omitempty"`
+	WriteFiles        []cloudConfigFile `json:"write_files,omitempty"`
+	RunCmd            []string          `json:"runcmd,omitempty"`
+}
+
+type cloudConfigUser struct {
+	Name              string   `json:"name"`
+	Groups            string   `json:"groups,omitempty"`
+	Sudo              string   `json:"sudo,omitempty"`
+	Shell             string   `json:"shell,omitempty"`
+	SSHAuthorizedKeys []string `json:"ssh_authorized_keys,omitempty"`
+}
+
+type cloudConfigFile struct {
+	Path        string `json:"path"`
+	Content     string `json:"content"`
+	Permissions string `json:"permissions,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+}
+
+// RenderCloudInit renders the cloud-init configuration into a MIME multipart document
+// holding the cloud-config followed by any extra parts
+func RenderCloudInit(config ec2v1alpha1.CloudInit) ([]byte, error) {
+	cloudConfig, err := renderCloudConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(cloudInitBoundary); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=\"%s\"\r\nMIME-Version: 1.0\r\n\r\n", cloudInitBoundary)
+
+	if err := writeCloudInitPart(writer, "text/cloud-config", "cloud-config.txt", cloudConfig); err != nil {
+		return nil, err
+	}
+	for i, part := range config.ExtraParts {
+		if len(part.ContentType) == 0 {
+			return nil, fmt.Errorf("Cloud-init part %d has no content type", i)
+		}
+		filename := part.Filename
+		if len(filename) == 0 {
+			filename = fmt.Sprintf("part-%03d", i+1)
+		}
+		if err := writeCloudInitPart(writer, part.ContentType, filename, []byte(part.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderCloudConfig renders the #cloud-config part of the document
+func renderCloudConfig(config ec2v1alpha1.CloudInit) ([]byte, error) {
+	rendered := cloudConfig{
+		SSHAuthorizedKeys: config.SSHAuthorizedKeys,
+		Packages:          config.Packages,
+		RunCmd:            config.RunCmd,
+	}
+	for _, user := range config.Users {
+		if len(user.Name) == 0 {
+			return nil, fmt.Errorf("Cloud-init user has no name")
+		}
+		rendered.Users = append(rendered.Users, cloudConfigUser{
+			Name:              user.Name,
+			Groups:            strings.Join(user.Groups, ", "),
+			Sudo:              user.Sudo,
+			Shell:             user.Shell,
+			SSHAuthorizedKeys: user.SSHAuthorizedKeys,
+		})
+	}
+	for _, file := range config.WriteFiles {
+		if len(file.Path) == 0 {
+			return nil, fmt.Errorf("Cloud-init file has no path")
+		}
+		rendered.WriteFiles = append(rendered.WriteFiles, cloudConfigFile(file))
+	}
+
+	out, err := yaml.Marshal(rendered)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("#cloud-config\n"), out...), nil
+}
+
+// writeCloudInitPart adds a part to the document as an attachment, the way cloud-init
+// expects to find it
+func writeCloudInitPart(writer *multipart.Writer, contentType, filename string, content []byte) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", contentType))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Transfer-Encoding", "7bit")
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(content)
+	return err
+}
@@ -0,0 +1,137 @@
+package ec2
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+func TestRenderCloudInit(t *testing.T) {
+	config := ec2v1alpha1.CloudInit{
+		Users: []ec2v1alpha1.CloudInitUser{
+			{
+				Name:              "ops",
+				Groups:            []string{"wheel", "docker"},
+				Sudo:              "ALL=(ALL) NOPASSWD:ALL",
+				SSHAuthorizedKeys: []string{"ssh-rsa AAAA ops"},
+			},
+		},
+		Packages: []string{"curl"},
+		WriteFiles: []ec2v1alpha1.CloudInitFile{
+			{Path: "/etc/motd", Content: "hello\n", Permissions: "0644"},
+		},
+		RunCmd: []string{"systemctl restart sshd"},
+		ExtraParts: []ec2v1alpha1.CloudInitPart{
+			{ContentType: "text/x-shellscript", Content: "#!/bin/sh\necho done\n"},
+		},
+	}
+
+	rendered, err := RenderCloudInit(config)
+	if err != nil {
+		t.Fatalf("RenderCloudInit returned an error: %v", err)
+	}
+
+	parts := readParts(t, rendered)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+
+	if parts[0].contentType != "text/cloud-config" {
+		t.Errorf("expected the first part to be text/cloud-config, got %s", parts[0].contentType)
+	}
+	for _, expected := range []string{
+		"#cloud-config\n",
+		"name: ops",
+		"groups: wheel, docker",
+		"- ssh-rsa AAAA ops",
+		"packages:\n- curl",
+		"path: /etc/motd",
+		"permissions: \"0644\"",
+		"runcmd:\n- systemctl restart sshd",
+	} {
+		if !strings.Contains(parts[0].content, expected) {
+			t.Errorf("expected cloud-config to contain %q, got:\n%s", expected, parts[0].content)
+		}
+	}
+
+	if parts[1].contentType != "text/x-shellscript" {
+		t.Errorf("expected the second part to be text/x-shellscript, got %s", parts[1].contentType)
+	}
+	if parts[1].filename != "part-001" {
+		t.Errorf("expected the default filename part-001, got %s", parts[1].filename)
+	}
+	if parts[1].content != "#!/bin/sh\necho done\n" {
+		t.Errorf("unexpected extra part content %q", parts[1].content)
+	}
+}
+
+func TestRenderCloudInitIsStable(t *testing.T) {
+	config := ec2v1alpha1.CloudInit{Packages: []string{"curl"}}
+	first, err := RenderCloudInit(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := RenderCloudInit(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("rendering the same configuration twice gave different documents")
+	}
+}
+
+func TestRenderCloudInitErrors(t *testing.T) {
+	for name, config := range map[string]ec2v1alpha1.CloudInit{
+		"user without name": {Users: []ec2v1alpha1.CloudInitUser{{Shell: "/bin/bash"}}},
+		"file without path": {WriteFiles: []ec2v1alpha1.CloudInitFile{{Content: "x"}}},
+		"part without type": {ExtraParts: []ec2v1alpha1.CloudInitPart{{Content: "x"}}},
+	} {
+		if _, err := RenderCloudInit(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+type renderedPart struct {
+	contentType string
+	filename    string
+	content     string
+}
+
+// readParts parses the rendered document the way cloud-init does
+func readParts(t *testing.T, document []byte) []renderedPart {
+	message, err := mail.ReadMessage(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("unable to parse document: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected a multipart/mixed document, got %q: %v", mediaType, err)
+	}
+
+	var parts []renderedPart
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts = append(parts, renderedPart{
+			contentType: contentType,
+			filename:    part.FileName(),
+			content:     string(content),
+		})
+	}
+	return parts
+}
@@ -0,0 +1,67 @@
+package ec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// CreditSpecificationPending is true when the credit option applied to the instance
+// does not match the spec
+func CreditSpecificationPending(instance ec2v1alpha1.Instance) bool {
+	return instance.Spec.CreditSpecification != nil && instance.Spec.CreditSpecification.CPUCredits != instance.Status.CPUCredits
+}
+
+// creditSpecificationFailed starts the status message of a failed credit option update
+const creditSpecificationFailed = "Unable to set cpu credits"
+
+// ReconcileCreditSpecification applies the credit option of the spec to the instance
+func (a *AWSClient) ReconcileCreditSpecification(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	status = *instance.Status.DeepCopy()
+	cpuCredits := instance.Spec.CreditSpecification.CPUCredits
+	output, err := a.svc.ModifyInstanceCreditSpecification(&awsec2.ModifyInstanceCreditSpecificationInput{
+		InstanceCreditSpecifications: []*awsec2.InstanceCreditSpecificationRequest{
+			{
+				InstanceId: aws.String(instance.Status.InstanceID),
+				CpuCredits: aws.String(cpuCredits),
+			},
+		},
+	})
+	if err != nil {
+		err = fmt.Errorf("%s to %s: %v", creditSpecificationFailed, cpuCredits, err)
+	} else if len(output.UnsuccessfulInstanceCreditSpecifications) > 0 {
+		failure := output.UnsuccessfulInstanceCreditSpecifications[0]
+		err = fmt.Errorf("%s to %s", creditSpecificationFailed, cpuCredits)
+		if failure.Error != nil {
+			err = fmt.Errorf("%s to %s: %s", creditSpecificationFailed, cpuCredits, aws.StringValue(failure.Error.Message))
+		}
+	}
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+
+	status.CPUCredits = cpuCredits
+	// only clear the message if it was left by a failed credit option update
+	if strings.HasPrefix(status.Message, creditSpecificationFailed) {
+		status.Message = ""
+	}
+	return status, nil
+}
+
+// cpuCredits returns the credit option applied to a burstable instance
+func (a *AWSClient) cpuCredits(instanceID string) (string, error) {
+	output, err := a.svc.DescribeInstanceCreditSpecifications(&awsec2.DescribeInstanceCreditSpecificationsInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.InstanceCreditSpecifications) == 0 {
+		return "", nil
+	}
+	return aws.StringValue(output.InstanceCreditSpecifications[0].CpuCredits), nil
+}
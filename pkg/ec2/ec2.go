@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws/session"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
 	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
@@ -19,8 +20,29 @@ var (
 	Provisioned     = "provisioned"
 	WaitForPublicIP = "waitforpublicip"
 	WaitForTag      = "waitfortag"
+	Error           = "error"
+	SpotInterrupted = "spotinterrupted"
+	Starting        = "starting"
+	Stopping        = "stopping"
+	Stopped         = "stopped"
+	Hibernated      = "hibernated"
+	Resizing        = "resizing"
+	Terminated      = "terminated"
+	Terminating     = "terminating"
+	Creating        = "creating"
+	Attaching       = "attaching"
+	Detaching       = "detaching"
 )
 
+// State reason codes set by EC2 when it interrupts a spot instance
+const (
+	spotInstanceTermination = "Server.SpotInstanceTermination"
+	spotInstanceShutdown    = "Server.SpotInstanceShutdown"
+)
+
+// instanceNotFound is the error code returned by EC2 for unknown instance ids
+const instanceNotFound = "InvalidInstanceID.NotFound"
+
 type AWSClient struct {
 	svc *awsec2.EC2
 }
@@ -45,41 +67,295 @@ func NewAWSClient(secret corev1.Secret, region string) (a *AWSClient, err error)
 	return a, nil
 }
 
-// CreateInstance will take the instance spec and delete the instance //
-func (a *AWSClient) CreateInstance(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+// CreateInstance will take the instance spec and launch the instance //
+// userData is the base64 encoded user data resolved for the instance.
+// Launches are idempotent: RunInstances is called with a client token derived from the
+// object, and an instance already launched for the object is looked up by its token or
+// ownership tag before a new one is launched.
+func (a *AWSClient) CreateInstance(instance ec2v1alpha1.Instance, userData string) (status ec2v1alpha1.InstanceStatus, err error) {
 	// For instances that are edited.. we are not going to do ignore //
-	var reservation *awsec2.Reservation
 	if instance.Status.Status == "Provisioned" && len(instance.Status.InstanceID) > 0 {
 		return instance.Status, nil
 	}
 
-	if instance.Status.Status == "" {
-		runInput := &awsec2.RunInstancesInput{
-			ImageId:      aws.String(instance.Spec.ImageID),
-			InstanceType: aws.String(instance.Spec.InstanceType),
-			MinCount:     aws.Int64(1),
-			MaxCount:     aws.Int64(1),
-			SubnetId:     aws.String(instance.Spec.SubnetID),
-			IamInstanceProfile: &awsec2.IamInstanceProfileSpecification{
-				Arn: aws.String(instance.Spec.IAMInstanceProfile),
+	status.Relaunches = instance.Status.Relaunches
+	status.ElasticIP = instance.Status.ElasticIP
+	// the image resolved from the selector is kept, so a retried launch uses the same one
+	status.ImageID = instance.Status.ImageID
+	token := ClientToken(instance)
+
+	existing, err := a.findLaunchedInstance(instance, token)
+	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+	if existing != nil {
+		return launchedStatus(instance, status, existing), nil
+	}
+
+	imageID, err := a.launchImageID(instance)
+	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+
+	if instance.Spec.LaunchTemplate == nil && (len(imageID) == 0 || len(instance.Spec.InstanceType) == 0) {
+		err = fmt.Errorf("imageID or imageSelector, and instanceType are required to launch an instance without a launch template")
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+
+	// fields left unset in the spec are taken from the launch template, if any
+	runInput := &awsec2.RunInstancesInput{
+		ClientToken: aws.String(token),
+		MinCount:    aws.Int64(1),
+		MaxCount:    aws.Int64(1),
+		TagSpecifications: []*awsec2.TagSpecification{
+			{
+				ResourceType: aws.String(awsec2.ResourceTypeInstance),
+				Tags:         launchTags(instance),
 			},
-			UserData:         aws.String(instance.Spec.UserData),
-			SecurityGroupIds: aws.StringSlice(instance.Spec.SecurityGroupIDS),
-		}
-		if len(instance.Spec.KeyName) > 0 {
-			runInput = runInput.SetKeyName(instance.Spec.KeyName)
+		},
+	}
+	if instance.Spec.LaunchTemplate != nil {
+		launchTemplate, err := a.resolveLaunchTemplate(instance.Spec.LaunchTemplate)
+		if err != nil {
+			status.Status = Error
+			status.Message = err.Error()
+			return status, err
 		}
-		reservation, err = a.svc.RunInstances(runInput)
+		status.LaunchTemplate = launchTemplate
+		runInput = runInput.SetLaunchTemplate(&awsec2.LaunchTemplateSpecification{
+			LaunchTemplateId: aws.String(launchTemplate.ID),
+			Version:          aws.String(launchTemplate.Version),
+		})
+	}
+	if len(imageID) > 0 {
+		runInput = runInput.SetImageId(imageID)
+	}
+	if len(instance.Spec.InstanceType) > 0 {
+		runInput = runInput.SetInstanceType(instance.Spec.InstanceType)
+	}
+	if len(instance.Spec.IAMInstanceProfile) > 0 {
+		runInput = runInput.SetIamInstanceProfile(&awsec2.IamInstanceProfileSpecification{
+			Arn: aws.String(instance.Spec.IAMInstanceProfile),
+		})
+	}
+	if len(userData) > 0 {
+		runInput = runInput.SetUserData(userData)
 	}
 
+	// EC2 does not accept a subnet and security groups for the instance alongside
+	// network interfaces, so they are applied to the interfaces instead
+	if len(instance.Spec.NetworkInterfaces) > 0 {
+		interfaces, err := a.networkInterfaces(instance)
+		if err != nil {
+			status.Status = Error
+			status.Message = err.Error()
+			return status, err
+		}
+		runInput = runInput.SetNetworkInterfaces(interfaces)
+	} else {
+		securityGroupIDs, err := a.securityGroupIDs(instance)
+		if err != nil {
+			status.Status = Error
+			status.Message = err.Error()
+			return status, err
+		}
+		if len(instance.Spec.SubnetID) > 0 {
+			runInput = runInput.SetSubnetId(instance.Spec.SubnetID)
+		}
+		if len(securityGroupIDs) > 0 {
+			runInput = runInput.SetSecurityGroupIds(aws.StringSlice(securityGroupIDs))
+		}
+	}
+	if len(instance.Spec.KeyName) > 0 {
+		runInput = runInput.SetKeyName(instance.Spec.KeyName)
+	}
+	if len(instance.Spec.BlockDeviceMappings) > 0 {
+		runInput = runInput.SetBlockDeviceMappings(blockDeviceMappings(instance.Spec.BlockDeviceMappings))
+	}
+	if instance.Spec.MarketOptions != nil {
+		runInput = runInput.SetInstanceMarketOptions(marketOptions(instance.Spec.MarketOptions))
+	}
+	if instance.Spec.CPUOptions != nil {
+		cpuOptions := &awsec2.CpuOptionsRequest{CoreCount: aws.Int64(instance.Spec.CPUOptions.CoreCount)}
+		if instance.Spec.CPUOptions.ThreadsPerCore > 0 {
+			cpuOptions.ThreadsPerCore = aws.Int64(instance.Spec.CPUOptions.ThreadsPerCore)
+		}
+		runInput = runInput.SetCpuOptions(cpuOptions)
+	}
+	if instance.Spec.CreditSpecification != nil {
+		runInput = runInput.SetCreditSpecification(&awsec2.CreditSpecificationRequest{
+			CpuCredits: aws.String(instance.Spec.CreditSpecification.CPUCredits),
+		})
+	}
+	if instance.Spec.Placement != nil {
+		placement, err := a.placement(instance)
+		if err != nil {
+			status.Status = Error
+			status.Message = err.Error()
+			return status, err
+		}
+		runInput = runInput.SetPlacement(placement)
+	}
+	if instance.Spec.MetadataOptions != nil {
+		runInput = runInput.SetMetadataOptions(metadataOptionsRequest(instance.Spec.MetadataOptions))
+	}
+	if instance.Spec.EnableHibernation {
+		runInput = runInput.SetHibernationOptions(&awsec2.HibernationOptionsRequest{Configured: aws.Bool(true)})
+	}
+	reservation, err := a.svc.RunInstances(runInput)
 	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
 		return status, err
 	}
+	if instance.Spec.CreditSpecification != nil {
+		status.CPUCredits = instance.Spec.CreditSpecification.CPUCredits
+	}
+
+	return launchedStatus(instance, status, reservation.Instances[0]), nil
+}
+
+// ClientToken is the idempotency token used to launch the instance. It changes with the
+// generation of the object, as EC2 rejects a token reused with different parameters, and
+// with every relaunch of a terminated spot instance.
+func ClientToken(instance ec2v1alpha1.Instance) string {
+	return fmt.Sprintf("%s-%d-%d", instance.UID, instance.Generation, instance.Status.Relaunches)
+}
+
+// LaunchedInstanceID returns the id of a live instance launched for the object whose id
+// was never recorded in the status, or an empty string when there is none
+func (a *AWSClient) LaunchedInstanceID(instance ec2v1alpha1.Instance) (string, error) {
+	ec2Instance, err := a.findLaunchedInstance(instance, ClientToken(instance))
+	if err != nil || ec2Instance == nil {
+		return "", err
+	}
+	return aws.StringValue(ec2Instance.InstanceId), nil
+}
 
-	status.InstanceID = *reservation.Instances[0].InstanceId
-	status.PrivateIP = *reservation.Instances[0].PrivateIpAddress
+// findLaunchedInstance looks for a live instance launched for the object, either with
+// the client token or carrying the ownership tag of the object
+func (a *AWSClient) findLaunchedInstance(instance ec2v1alpha1.Instance, token string) (*awsec2.Instance, error) {
+	liveStates := aws.StringSlice([]string{
+		awsec2.InstanceStateNamePending,
+		awsec2.InstanceStateNameRunning,
+		awsec2.InstanceStateNameStopping,
+		awsec2.InstanceStateNameStopped,
+	})
+
+	for _, filter := range []*awsec2.Filter{
+		{Name: aws.String("client-token"), Values: aws.StringSlice([]string{token})},
+		{Name: aws.String("tag:" + OwnerUIDTag), Values: aws.StringSlice([]string{string(instance.UID)})},
+	} {
+		output, err := a.svc.DescribeInstances(&awsec2.DescribeInstancesInput{
+			Filters: []*awsec2.Filter{
+				filter,
+				{Name: aws.String("instance-state-name"), Values: liveStates},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range output.Reservations {
+			if len(reservation.Instances) > 0 {
+				return reservation.Instances[0], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// launchedStatus records a newly launched instance in the status
+func launchedStatus(instance ec2v1alpha1.Instance, status ec2v1alpha1.InstanceStatus, ec2Instance *awsec2.Instance) ec2v1alpha1.InstanceStatus {
+	status.InstanceID = aws.StringValue(ec2Instance.InstanceId)
+	status.ImageID = aws.StringValue(ec2Instance.ImageId)
+	status.PrivateIP = aws.StringValue(ec2Instance.PrivateIpAddress)
+	status.PrivateDNS = aws.StringValue(ec2Instance.PrivateDnsName)
+	status.InstanceState = aws.StringValue(ec2Instance.State.Name)
+	status.InstanceType = aws.StringValue(ec2Instance.InstanceType)
+	status.SpotInstanceRequestID = aws.StringValue(ec2Instance.SpotInstanceRequestId)
+	status.SecurityGroupIDS = nil
+	for _, group := range ec2Instance.SecurityGroups {
+		status.SecurityGroupIDS = append(status.SecurityGroupIDS, aws.StringValue(group.GroupId))
+	}
+	status.NetworkInterfaces = networkInterfaceStatus(ec2Instance)
+	status.MetadataOptions = metadataOptionsStatus(ec2Instance.MetadataOptions)
+	if ec2Instance.CpuOptions != nil {
+		status.CPUOptions = &ec2v1alpha1.CPUOptions{
+			CoreCount:      aws.Int64Value(ec2Instance.CpuOptions.CoreCount),
+			ThreadsPerCore: aws.Int64Value(ec2Instance.CpuOptions.ThreadsPerCore),
+		}
+	}
+	if ec2Instance.Placement != nil {
+		status.AvailabilityZone = aws.StringValue(ec2Instance.Placement.AvailabilityZone)
+		status.PartitionNumber = aws.Int64Value(ec2Instance.Placement.PartitionNumber)
+	}
+	if launchTemplate := launchTemplateStatus(ec2Instance); launchTemplate != nil {
+		status.LaunchTemplate = launchTemplate
+	}
 	status.Status = WaitForTag
-	return status, nil
+	return status
+}
+
+// RemoveInstance handles the instance of a deleted object according to its deletion
+// policy: the instance is terminated, or stopped or left running after its ownership
+// tags have been removed. removed is only true once the instance has reached its final
+// state, so the caller keeps calling it until then. Instances that no longer exist are
+// considered removed.
+func (a *AWSClient) RemoveInstance(instance ec2v1alpha1.Instance) (removed bool, err error) {
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
+	if isNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	state := aws.StringValue(ec2Instance.State.Name)
+	if state == awsec2.InstanceStateNameTerminated {
+		return true, nil
+	}
+
+	switch instance.Spec.DeletionPolicy {
+	case ec2v1alpha1.DeletionPolicyRetain:
+		return true, a.releaseInstance(instance)
+	case ec2v1alpha1.DeletionPolicyStop:
+		switch state {
+		case awsec2.InstanceStateNameStopped:
+			return true, a.releaseInstance(instance)
+		case awsec2.InstanceStateNameRunning:
+			_, err = a.svc.StopInstances(&awsec2.StopInstancesInput{
+				InstanceIds: aws.StringSlice([]string{instance.Status.InstanceID}),
+			})
+		}
+	default:
+		if state != awsec2.InstanceStateNameShuttingDown {
+			err = a.DeleteInstance(instance)
+		}
+	}
+
+	if isNotFound(err) {
+		return true, nil
+	}
+	return false, err
+}
+
+// releaseInstance removes the ownership tags so the instance is no longer tied to the object
+func (a *AWSClient) releaseInstance(instance ec2v1alpha1.Instance) (err error) {
+	_, err = a.svc.DeleteTags(&awsec2.DeleteTagsInput{
+		Resources: aws.StringSlice([]string{instance.Status.InstanceID}),
+		Tags: []*awsec2.Tag{
+			{Key: aws.String(OwnerUIDTag)},
+			{Key: aws.String(OwnerTag)},
+		},
+	})
+	return err
 }
 
 // DeleteInstance will remove the instance and
@@ -117,6 +393,7 @@ func (a *AWSClient) FetchPublicIP(instance ec2v1alpha1.Instance) (status ec2v1al
 	status = *instance.Status.DeepCopy()
 	if describeInstanceOuput.Reservations[0].Instances[0].PublicIpAddress != nil {
 		status.PublicIP = *describeInstanceOuput.Reservations[0].Instances[0].PublicIpAddress
+		status.PublicDNS = aws.StringValue(describeInstanceOuput.Reservations[0].Instances[0].PublicDnsName)
 		status.Status = Provisioned
 		return status, nil
 	}
@@ -128,25 +405,22 @@ func (a *AWSClient) FetchPublicIP(instance ec2v1alpha1.Instance) (status ec2v1al
 
 func (a *AWSClient) UpdateTags(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
 	// tag instance //
-	tags := []*awsec2.Tag{}
-
-	for _, tagDetails := range instance.Spec.TagSpecifications {
-		tags = append(tags, &awsec2.Tag{Key: aws.String(tagDetails.Name), Value: aws.String(tagDetails.Value)})
+	managedTags, err := a.reconcileTags(instance.Status.InstanceID, instanceTags(instance), instance.Status.ManagedTags)
+	if err != nil {
+		return status, err
 	}
-	//Default tag
-	tags = append(tags, &awsec2.Tag{Key: aws.String("Name"), Value: aws.String(instance.ObjectMeta.Name)})
-
-	_, err = a.svc.CreateTags(&awsec2.CreateTagsInput{
-		Resources: []*string{aws.String(instance.Status.InstanceID)},
-		Tags:      tags,
-	})
 
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
 	if err != nil {
 		return status, err
 	}
 
 	status = *instance.Status.DeepCopy()
-	if instance.Spec.PublicIPAddress {
+	status.BlockDevices = blockDeviceStatus(ec2Instance)
+	status.NetworkInterfaces = networkInterfaceStatus(ec2Instance)
+	status.ManagedTags = managedTags
+	ec2v1alpha1.SetCondition(&status.Conditions, tagsSyncedCondition(instance.Generation, nil))
+	if instance.Spec.PublicIPAddress || instance.Spec.ElasticIP != nil {
 		status.Status = WaitForPublicIP
 	} else {
 		status.Status = Provisioned
@@ -154,19 +428,468 @@ func (a *AWSClient) UpdateTags(instance ec2v1alpha1.Instance) (status ec2v1alpha
 	return status, nil
 }
 
+// CheckSpotInstance looks for interruptions of a provisioned spot instance by EC2.
+// Interrupted instances are moved to the SpotInterrupted status, and back to
+// Provisioned once a persistent request has brought them back to running.
+func (a *AWSClient) CheckSpotInstance(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
+	if err != nil {
+		return status, err
+	}
+
+	status = *instance.Status.DeepCopy()
+	status.InstanceState = aws.StringValue(ec2Instance.State.Name)
+
+	var reason, message string
+	if ec2Instance.StateReason != nil {
+		reason = aws.StringValue(ec2Instance.StateReason.Code)
+		message = aws.StringValue(ec2Instance.StateReason.Message)
+	}
+
+	switch {
+	case status.InstanceState == awsec2.InstanceStateNameRunning:
+		status.Status = Provisioned
+		status.Message = ""
+	case reason == spotInstanceTermination || reason == spotInstanceShutdown:
+		status.Status = SpotInterrupted
+		status.Message = message
+	}
+
+	return status, nil
+}
+
+// DesiredStatus returns the status a provisioned instance settles in for its power state
+func DesiredStatus(instance ec2v1alpha1.Instance) string {
+	switch instance.Spec.PowerState {
+	case ec2v1alpha1.PowerStateStopped:
+		return Stopped
+	case ec2v1alpha1.PowerStateHibernated:
+		return Hibernated
+	default:
+		return Provisioned
+	}
+}
+
+// ReconcilePowerState starts or stops a provisioned instance to match spec.powerState.
+// The instance is left in the Starting or Stopping status until EC2 has completed
+// the transition.
+func (a *AWSClient) ReconcilePowerState(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
+	if err != nil {
+		return status, err
+	}
+
+	status = *instance.Status.DeepCopy()
+	status.InstanceState = aws.StringValue(ec2Instance.State.Name)
+	status.InstanceType = aws.StringValue(ec2Instance.InstanceType)
+	status.PrivateIP = aws.StringValue(ec2Instance.PrivateIpAddress)
+	status.PublicIP = aws.StringValue(ec2Instance.PublicIpAddress)
+	status.Message = ""
+
+	if status.InstanceState == awsec2.InstanceStateNameShuttingDown || status.InstanceState == awsec2.InstanceStateNameTerminated {
+		err = fmt.Errorf("Instance %s is %s and can not be started or stopped", instance.Status.InstanceID, status.InstanceState)
+		status.Message = err.Error()
+		return status, err
+	}
+
+	desired := DesiredStatus(instance)
+	if desired == Provisioned {
+		switch status.InstanceState {
+		case awsec2.InstanceStateNameRunning:
+			status.Status = Provisioned
+		case awsec2.InstanceStateNameStopped:
+			_, err = a.svc.StartInstances(&awsec2.StartInstancesInput{
+				InstanceIds: aws.StringSlice([]string{instance.Status.InstanceID}),
+			})
+			status.Status = Starting
+		default:
+			// wait for pending or stopping to complete
+			status.Status = Starting
+		}
+	} else {
+		switch status.InstanceState {
+		case awsec2.InstanceStateNameStopped:
+			status.Status = desired
+		case awsec2.InstanceStateNameRunning:
+			_, err = a.svc.StopInstances(&awsec2.StopInstancesInput{
+				InstanceIds: aws.StringSlice([]string{instance.Status.InstanceID}),
+				Hibernate:   aws.Bool(desired == Hibernated),
+			})
+			status.Status = Stopping
+		default:
+			status.Status = Stopping
+		}
+	}
+
+	if err != nil {
+		status.Status = instance.Status.Status
+		status.Message = err.Error()
+	}
+
+	return status, err
+}
+
+// ResizeInstance changes the type of a provisioned instance to spec.instanceType. The
+// instance is stopped, modified and handed back to the power state reconcile, which
+// starts it again if it is meant to be running. The instance stays in the Resizing
+// status while it is being stopped.
+func (a *AWSClient) ResizeInstance(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
+	if err != nil {
+		return status, err
+	}
+
+	status = *instance.Status.DeepCopy()
+	status.InstanceState = aws.StringValue(ec2Instance.State.Name)
+	status.InstanceType = aws.StringValue(ec2Instance.InstanceType)
+
+	if status.InstanceType == instance.Spec.InstanceType {
+		status.Message = ""
+		if status.Status == Resizing {
+			status.Status = Stopped
+		}
+		return status, nil
+	}
+
+	if instance.Spec.MarketOptions != nil {
+		err = fmt.Errorf("Instance type of spot instance %s can not be changed", instance.Status.InstanceID)
+		status.Message = err.Error()
+		return status, err
+	}
+
+	switch status.InstanceState {
+	case awsec2.InstanceStateNameRunning:
+		_, err = a.svc.StopInstances(&awsec2.StopInstancesInput{
+			InstanceIds: aws.StringSlice([]string{instance.Status.InstanceID}),
+		})
+		status.Status = Resizing
+		status.Message = fmt.Sprintf("Stopping instance to change type from %s to %s", status.InstanceType, instance.Spec.InstanceType)
+	case awsec2.InstanceStateNameStopped:
+		_, err = a.svc.ModifyInstanceAttribute(&awsec2.ModifyInstanceAttributeInput{
+			InstanceId:   aws.String(instance.Status.InstanceID),
+			InstanceType: &awsec2.AttributeValue{Value: aws.String(instance.Spec.InstanceType)},
+		})
+		if err == nil {
+			// the power state reconcile will start the instance if needed
+			status.Status = Stopped
+			status.InstanceType = instance.Spec.InstanceType
+			status.Message = ""
+		}
+	case awsec2.InstanceStateNameShuttingDown, awsec2.InstanceStateNameTerminated:
+		err = fmt.Errorf("Instance %s is %s and can not be resized", instance.Status.InstanceID, status.InstanceState)
+	default:
+		// wait for pending or stopping to complete
+		status.Status = Resizing
+	}
+
+	if err != nil {
+		status.Message = err.Error()
+	}
+
+	return status, err
+}
+
+// marketOptions converts the spec market options to the RunInstances format
+func marketOptions(options *ec2v1alpha1.MarketOptions) *awsec2.InstanceMarketOptionsRequest {
+	request := &awsec2.InstanceMarketOptionsRequest{
+		MarketType: aws.String(options.MarketType),
+	}
+	if options.SpotOptions == nil {
+		return request
+	}
+
+	spotOptions := &awsec2.SpotMarketOptions{}
+	if len(options.SpotOptions.MaxPrice) > 0 {
+		spotOptions.MaxPrice = aws.String(options.SpotOptions.MaxPrice)
+	}
+	if len(options.SpotOptions.InstanceInterruptionBehavior) > 0 {
+		spotOptions.InstanceInterruptionBehavior = aws.String(options.SpotOptions.InstanceInterruptionBehavior)
+	}
+	if len(options.SpotOptions.SpotInstanceType) > 0 {
+		spotOptions.SpotInstanceType = aws.String(options.SpotOptions.SpotInstanceType)
+	}
+	return request.SetSpotOptions(spotOptions)
+}
+
+// SyncInstance refreshes the status from the live instance and returns the differences
+// between the live instance and the spec. When correct is set, tags are re-applied and
+// the status is updated to the live power state and instance type so that the power
+// state and resize reconciles bring the instance back in line with the spec.
+func (a *AWSClient) SyncInstance(instance ec2v1alpha1.Instance, correct bool) (status ec2v1alpha1.InstanceStatus, drift []string, err error) {
+	status = *instance.Status.DeepCopy()
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
+	if isNotFound(err) {
+		status.InstanceState = awsec2.InstanceStateNameTerminated
+		return instanceLost(instance, status), []string{fmt.Sprintf("instance %s no longer exists", instance.Status.InstanceID)}, nil
+	}
+	if err != nil {
+		return status, nil, err
+	}
+
+	state := aws.StringValue(ec2Instance.State.Name)
+	status.InstanceState = state
+	status.PrivateIP = aws.StringValue(ec2Instance.PrivateIpAddress)
+	status.PublicIP = aws.StringValue(ec2Instance.PublicIpAddress)
+	status.PrivateDNS = aws.StringValue(ec2Instance.PrivateDnsName)
+	status.PublicDNS = aws.StringValue(ec2Instance.PublicDnsName)
+	status.BlockDevices = blockDeviceStatus(ec2Instance)
+	status.NetworkInterfaces = networkInterfaceStatus(ec2Instance)
+	status.MetadataOptions = metadataOptionsStatus(ec2Instance.MetadataOptions)
+	if instance.Spec.CreditSpecification != nil {
+		if status.CPUCredits, err = a.cpuCredits(instance.Status.InstanceID); err != nil {
+			return status, nil, err
+		}
+	}
+
+	powerStateDrift := false
+	switch {
+	case state == awsec2.InstanceStateNameShuttingDown || state == awsec2.InstanceStateNameTerminated:
+		return instanceLost(instance, status), []string{fmt.Sprintf("instance is %s", state)}, nil
+	case DesiredStatus(instance) == Provisioned && state != awsec2.InstanceStateNameRunning && state != awsec2.InstanceStateNamePending:
+		drift = append(drift, fmt.Sprintf("instance is %s, expected running", state))
+		powerStateDrift = true
+	case DesiredStatus(instance) != Provisioned && state != awsec2.InstanceStateNameStopped && state != awsec2.InstanceStateNameStopping:
+		drift = append(drift, fmt.Sprintf("instance is %s, expected stopped", state))
+		powerStateDrift = true
+	}
+
+	liveType := aws.StringValue(ec2Instance.InstanceType)
+	if len(status.InstanceType) > 0 && liveType != status.InstanceType {
+		drift = append(drift, fmt.Sprintf("instance type is %s, expected %s", liveType, status.InstanceType))
+	}
+
+	liveTags := make(map[string]string)
+	for _, tag := range ec2Instance.Tags {
+		liveTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	tagDrift := false
+	desiredKeys := make(map[string]bool)
+	for _, tag := range instanceTags(instance) {
+		key, value := aws.StringValue(tag.Key), aws.StringValue(tag.Value)
+		desiredKeys[key] = true
+		if live, ok := liveTags[key]; !ok {
+			drift = append(drift, fmt.Sprintf("tag %s is missing", key))
+			tagDrift = true
+		} else if live != value {
+			drift = append(drift, fmt.Sprintf("tag %s is %q, expected %q", key, live, value))
+			tagDrift = true
+		}
+	}
+	for _, key := range instance.Status.ManagedTags {
+		if _, ok := liveTags[key]; ok && !desiredKeys[key] {
+			drift = append(drift, fmt.Sprintf("tag %s is no longer in the spec", key))
+			tagDrift = true
+		}
+	}
+
+	if !correct || len(drift) == 0 {
+		return status, drift, nil
+	}
+
+	if tagDrift {
+		managedTags, err := a.reconcileTags(instance.Status.InstanceID, instanceTags(instance), instance.Status.ManagedTags)
+		if err != nil {
+			return status, drift, err
+		}
+		status.ManagedTags = managedTags
+	}
+
+	if powerStateDrift && status.Status != SpotInterrupted {
+		if state == awsec2.InstanceStateNameRunning {
+			status.Status = Provisioned
+		} else if state == awsec2.InstanceStateNameStopped {
+			status.Status = Stopped
+		}
+	}
+
+	if len(status.InstanceType) > 0 && liveType != status.InstanceType {
+		status.InstanceType = liveType
+	}
+
+	return status, drift, nil
+}
+
+// instanceLost moves an instance that was terminated outside of the operator to the
+// Terminated status, as this drift can not be corrected. Spot instances are left to the
+// spot interruption check, which relaunches them when requested.
+func instanceLost(instance ec2v1alpha1.Instance, status ec2v1alpha1.InstanceStatus) ec2v1alpha1.InstanceStatus {
+	if instance.Spec.MarketOptions != nil {
+		return status
+	}
+	status.Status = Terminated
+	status.Message = fmt.Sprintf("Instance %s was terminated outside of the operator", instance.Status.InstanceID)
+	return status
+}
+
+// isNotFound is true for errors returned by EC2 for instances that no longer exist
+func isNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == instanceNotFound
+	}
+	return false
+}
+
+// securityGroupIDs returns the security group ids from the spec along with the ids
+// of the named security groups in the VPC of the instance subnet
+func (a *AWSClient) securityGroupIDs(instance ec2v1alpha1.Instance) (ids []string, err error) {
+	return a.resolveSecurityGroups(instance.Spec.SubnetID, instance.Spec.SecurityGroupIDS, instance.Spec.SecurityGroups)
+}
+
+// resolveSecurityGroups combines the security group ids with the ids of the named groups
+// in the VPC of the subnet
+func (a *AWSClient) resolveSecurityGroups(subnetID string, groupIDs []string, names []string) (ids []string, err error) {
+	ids = append(ids, groupIDs...)
+	if len(names) == 0 {
+		return ids, nil
+	}
+
+	vpcID, err := a.vpcID(subnetID)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := a.svc.DescribeSecurityGroups(&awsec2.DescribeSecurityGroupsInput{
+		Filters: []*awsec2.Filter{
+			{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{vpcID})},
+			{Name: aws.String("group-name"), Values: aws.StringSlice(names)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, group := range output.SecurityGroups {
+		name := aws.StringValue(group.GroupName)
+		groups[name] = append(groups[name], aws.StringValue(group.GroupId))
+	}
+
+	for _, name := range names {
+		switch matches := groups[name]; len(matches) {
+		case 0:
+			return nil, fmt.Errorf("Security group %s not found in vpc %s", name, vpcID)
+		case 1:
+			if !containsString(ids, matches[0]) {
+				ids = append(ids, matches[0])
+			}
+		default:
+			return nil, fmt.Errorf("Security group name %s is ambiguous in vpc %s: %v", name, vpcID, matches)
+		}
+	}
+
+	return ids, nil
+}
+
+// vpcID looks up the VPC of the subnet, or the default VPC when no subnet is specified
+func (a *AWSClient) vpcID(subnetID string) (string, error) {
+	if len(subnetID) == 0 {
+		output, err := a.svc.DescribeVpcs(&awsec2.DescribeVpcsInput{
+			Filters: []*awsec2.Filter{
+				{Name: aws.String("isDefault"), Values: aws.StringSlice([]string{"true"})},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(output.Vpcs) == 0 {
+			return "", fmt.Errorf("No subnet specified and no default vpc exists")
+		}
+		return aws.StringValue(output.Vpcs[0].VpcId), nil
+	}
+
+	output, err := a.svc.DescribeSubnets(&awsec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice([]string{subnetID}),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.Subnets) == 0 {
+		return "", fmt.Errorf("Subnet %s not found", subnetID)
+	}
+	return aws.StringValue(output.Subnets[0].VpcId), nil
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// describeInstance returns the current EC2 view of the instance
+func (a *AWSClient) describeInstance(instanceID string) (*awsec2.Instance, error) {
+	output, err := a.svc.DescribeInstances(&awsec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceID}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Reservations) == 0 || len(output.Reservations[0].Instances) == 0 {
+		return nil, awserr.New(instanceNotFound, fmt.Sprintf("Instance %s not found", instanceID), nil)
+	}
+
+	return output.Reservations[0].Instances[0], nil
+}
+
+// blockDeviceMappings converts the spec mappings to the RunInstances format
+func blockDeviceMappings(mappings []ec2v1alpha1.BlockDeviceMapping) (result []*awsec2.BlockDeviceMapping) {
+	for _, mapping := range mappings {
+		ebs := &awsec2.EbsBlockDevice{
+			DeleteOnTermination: mapping.DeleteOnTermination,
+		}
+		if mapping.VolumeSize > 0 {
+			ebs.VolumeSize = aws.Int64(mapping.VolumeSize)
+		}
+		if len(mapping.VolumeType) > 0 {
+			ebs.VolumeType = aws.String(mapping.VolumeType)
+		}
+		if mapping.IOPS > 0 {
+			ebs.Iops = aws.Int64(mapping.IOPS)
+		}
+		if mapping.Throughput > 0 {
+			ebs.Throughput = aws.Int64(mapping.Throughput)
+		}
+		if mapping.Encrypted {
+			ebs.Encrypted = aws.Bool(true)
+		}
+		if len(mapping.KMSKeyID) > 0 {
+			ebs.KmsKeyId = aws.String(mapping.KMSKeyID)
+		}
+		result = append(result, &awsec2.BlockDeviceMapping{
+			DeviceName: aws.String(mapping.DeviceName),
+			Ebs:        ebs,
+		})
+	}
+	return result
+}
+
+// blockDeviceStatus lists the volumes currently attached to the instance
+func blockDeviceStatus(ec2Instance *awsec2.Instance) (devices []ec2v1alpha1.BlockDeviceStatus) {
+	for _, mapping := range ec2Instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		devices = append(devices, ec2v1alpha1.BlockDeviceStatus{
+			DeviceName: aws.StringValue(mapping.DeviceName),
+			VolumeID:   aws.StringValue(mapping.Ebs.VolumeId),
+		})
+	}
+	return devices
+}
+
 func (a *AWSClient) ImportKeyPair(keypair ec2v1alpha1.ImportKeyPair) (status ec2v1alpha1.ImportKeyPairStatus, err error) {
 	if len(keypair.Spec.PublicKey) == 0 {
-		return status, fmt.Errorf("Empty KeyPair specified")
+		err = fmt.Errorf("Empty KeyPair specified")
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
 	}
 
 	// tag instance //
-	tags := []*awsec2.Tag{}
-
-	for _, tagDetails := range keypair.Spec.TagSpecifications {
-		tags = append(tags, &awsec2.Tag{Key: aws.String(tagDetails.Name), Value: aws.String(tagDetails.Value)})
-	}
-	//Default tag
-	tags = append(tags, &awsec2.Tag{Key: aws.String("Name"), Value: aws.String(keypair.Name)})
+	tags := keyPairTags(keypair)
 
 	output, err := a.svc.ImportKeyPair(&ec2.ImportKeyPairInput{
 		KeyName:           aws.String(keypair.Name),
@@ -179,12 +902,15 @@ func (a *AWSClient) ImportKeyPair(keypair ec2v1alpha1.ImportKeyPair) (status ec2
 	})
 
 	if err != nil {
-		status.Status = "error"
+		status.Status = Error
+		status.Message = err.Error()
 		return status, err
 	}
 
-	status.Status = "provisioned"
+	status.Status = Provisioned
 	status.KeyPairID = *output.KeyPairId
+	status.ManagedTags = tagKeys(tags)
+	ec2v1alpha1.SetCondition(&status.Conditions, tagsSyncedCondition(keypair.Generation, nil))
 
 	return status, nil
 }
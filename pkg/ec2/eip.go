@@ -0,0 +1,152 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// allocationNotFound is the error code returned for allocation ids that no longer exist
+const allocationNotFound = "InvalidAllocationID.NotFound"
+
+// AssociateElasticIP associates the Elastic IP address of the instance once it is
+// running, allocating the address first when the spec does not reference one. The
+// status is returned unchanged while the instance is still pending.
+func (a *AWSClient) AssociateElasticIP(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	status = *instance.Status.DeepCopy()
+	if status.ElasticIP == nil || len(status.ElasticIP.AllocationID) == 0 {
+		status.ElasticIP, err = a.elasticIPAllocation(instance)
+		if err != nil {
+			status.Message = err.Error()
+			return status, err
+		}
+	}
+
+	ec2Instance, err := a.describeInstance(instance.Status.InstanceID)
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+	state := aws.StringValue(ec2Instance.State.Name)
+	if state != awsec2.InstanceStateNameRunning && state != awsec2.InstanceStateNameStopped {
+		return status, nil
+	}
+
+	// AssociateAddress rejects an instance id when the instance has more than one
+	// network interface, so the address is associated with the primary interface
+	networkInterfaceID := primaryNetworkInterfaceID(status, ec2Instance)
+	if len(networkInterfaceID) == 0 {
+		err = fmt.Errorf("instance %s has no primary network interface", instance.Status.InstanceID)
+		status.Message = err.Error()
+		return status, err
+	}
+	output, err := a.svc.AssociateAddress(&awsec2.AssociateAddressInput{
+		AllocationId:       aws.String(status.ElasticIP.AllocationID),
+		NetworkInterfaceId: aws.String(networkInterfaceID),
+	})
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+
+	status.ElasticIP.AssociationID = aws.StringValue(output.AssociationId)
+	status.PublicIP = status.ElasticIP.PublicIP
+	status.Message = ""
+	if status.Status == WaitForPublicIP {
+		status.Status = Provisioned
+	}
+	return status, nil
+}
+
+// primaryNetworkInterfaceID returns the network interface at device index 0, from the
+// status or else from the described instance
+func primaryNetworkInterfaceID(status ec2v1alpha1.InstanceStatus, ec2Instance *awsec2.Instance) string {
+	for _, networkInterface := range status.NetworkInterfaces {
+		if networkInterface.DeviceIndex == 0 && len(networkInterface.NetworkInterfaceID) > 0 {
+			return networkInterface.NetworkInterfaceID
+		}
+	}
+	for _, networkInterface := range ec2Instance.NetworkInterfaces {
+		if networkInterface.Attachment != nil && aws.Int64Value(networkInterface.Attachment.DeviceIndex) == 0 {
+			return aws.StringValue(networkInterface.NetworkInterfaceId)
+		}
+	}
+	return ""
+}
+
+// elasticIPAllocation returns the allocation referenced in the spec, or the address
+// allocated for the object, allocating a new one when there is none. AllocateAddress
+// does not take tags, so the address is tagged with the ownership tags afterwards and
+// released again when that fails.
+func (a *AWSClient) elasticIPAllocation(instance ec2v1alpha1.Instance) (*ec2v1alpha1.ElasticIPStatus, error) {
+	filter := &awsec2.Filter{Name: aws.String("tag:" + OwnerUIDTag), Values: aws.StringSlice([]string{string(instance.UID)})}
+	if allocationID := instance.Spec.ElasticIP.AllocationID; len(allocationID) > 0 {
+		filter = &awsec2.Filter{Name: aws.String("allocation-id"), Values: aws.StringSlice([]string{allocationID})}
+	}
+
+	output, err := a.svc.DescribeAddresses(&awsec2.DescribeAddressesInput{
+		Filters: []*awsec2.Filter{filter},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Addresses) > 0 {
+		address := output.Addresses[0]
+		return &ec2v1alpha1.ElasticIPStatus{
+			AllocationID: aws.StringValue(address.AllocationId),
+			PublicIP:     aws.StringValue(address.PublicIp),
+			Allocated:    len(instance.Spec.ElasticIP.AllocationID) == 0,
+		}, nil
+	}
+	if len(instance.Spec.ElasticIP.AllocationID) > 0 {
+		return nil, fmt.Errorf("Elastic IP allocation %s not found", instance.Spec.ElasticIP.AllocationID)
+	}
+
+	allocation, err := a.svc.AllocateAddress(&awsec2.AllocateAddressInput{
+		Domain: aws.String(awsec2.DomainTypeVpc),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = a.svc.CreateTags(&awsec2.CreateTagsInput{
+		Resources: []*string{allocation.AllocationId},
+		Tags:      append(instanceTags(instance), ownershipTags(&instance)...),
+	})
+	if err != nil {
+		if _, releaseErr := a.svc.ReleaseAddress(&awsec2.ReleaseAddressInput{AllocationId: allocation.AllocationId}); releaseErr != nil {
+			return nil, fmt.Errorf("%v, and releasing Elastic IP %s failed: %v", err, aws.StringValue(allocation.AllocationId), releaseErr)
+		}
+		return nil, err
+	}
+
+	return &ec2v1alpha1.ElasticIPStatus{
+		AllocationID: aws.StringValue(allocation.AllocationId),
+		PublicIP:     aws.StringValue(allocation.PublicIp),
+		Allocated:    true,
+	}, nil
+}
+
+// ReleaseElasticIP releases the Elastic IP address allocated by the operator once the
+// instance has been removed. Addresses referenced in the spec, and addresses of
+// instances whose deletion policy is Retain or Stop, are kept.
+func (a *AWSClient) ReleaseElasticIP(instance ec2v1alpha1.Instance) (err error) {
+	eip := instance.Status.ElasticIP
+	if eip == nil || !eip.Allocated || len(eip.AllocationID) == 0 {
+		return nil
+	}
+	if len(instance.Spec.DeletionPolicy) > 0 && instance.Spec.DeletionPolicy != ec2v1alpha1.DeletionPolicyDelete {
+		return nil
+	}
+
+	_, err = a.svc.ReleaseAddress(&awsec2.ReleaseAddressInput{
+		AllocationId: aws.String(eip.AllocationID),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == allocationNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package ec2
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// launchImageID returns the image to launch the instance with. An image already pinned
+// in the status is reused, so a relaunched instance gets the same image even when the
+// selector now matches a newer one.
+func (a *AWSClient) launchImageID(instance ec2v1alpha1.Instance) (string, error) {
+	if len(instance.Spec.ImageID) > 0 && instance.Spec.ImageSelector != nil {
+		return "", fmt.Errorf("Only one of imageID and imageSelector can be specified")
+	}
+	if instance.Spec.ImageSelector == nil {
+		return instance.Spec.ImageID, nil
+	}
+	if len(instance.Status.ImageID) > 0 {
+		return instance.Status.ImageID, nil
+	}
+	return a.selectImage(instance.Spec.ImageSelector)
+}
+
+// selectImage returns the id of the available image matching the selector
+func (a *AWSClient) selectImage(selector *ec2v1alpha1.ImageSelector) (string, error) {
+	input := &awsec2.DescribeImagesInput{
+		Filters: []*awsec2.Filter{
+			{Name: aws.String("state"), Values: aws.StringSlice([]string{awsec2.ImageStateAvailable})},
+		},
+	}
+	if len(selector.Owners) > 0 {
+		input.Owners = aws.StringSlice(selector.Owners)
+	}
+	if len(selector.Name) > 0 {
+		input.Filters = append(input.Filters, &awsec2.Filter{Name: aws.String("name"), Values: aws.StringSlice([]string{selector.Name})})
+	}
+	if len(selector.Architecture) > 0 {
+		input.Filters = append(input.Filters, &awsec2.Filter{Name: aws.String("architecture"), Values: aws.StringSlice([]string{selector.Architecture})})
+	}
+	for key, value := range selector.Tags {
+		input.Filters = append(input.Filters, &awsec2.Filter{Name: aws.String("tag:" + key), Values: aws.StringSlice([]string{value})})
+	}
+
+	output, err := a.svc.DescribeImages(input)
+	if err != nil {
+		return "", err
+	}
+
+	images := output.Images
+	switch {
+	case len(images) == 0:
+		return "", fmt.Errorf("No image matches the imageSelector")
+	case len(images) > 1 && !selector.MostRecent:
+		return "", fmt.Errorf("%d images match the imageSelector, set mostRecent to pick the latest", len(images))
+	}
+
+	// creation dates are ISO 8601 timestamps, which sort as strings
+	sort.Slice(images, func(i, j int) bool {
+		return aws.StringValue(images[i].CreationDate) > aws.StringValue(images[j].CreationDate)
+	})
+	return aws.StringValue(images[0].ImageId), nil
+}
@@ -0,0 +1,70 @@
+package ec2
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// Tags EC2 adds to instances launched from a launch template
+const (
+	launchTemplateIDTag      = "aws:ec2launchtemplate:id"
+	launchTemplateVersionTag = "aws:ec2launchtemplate:version"
+)
+
+// resolveLaunchTemplate looks up the launch template version referenced in the spec.
+// $Latest and $Default are resolved to a version number, so the status records exactly
+// what the instance was launched from.
+func (a *AWSClient) resolveLaunchTemplate(spec *ec2v1alpha1.LaunchTemplateSpec) (*ec2v1alpha1.LaunchTemplateStatus, error) {
+	if (len(spec.ID) == 0) == (len(spec.Name) == 0) {
+		return nil, fmt.Errorf("launchTemplate needs exactly one of id or name")
+	}
+
+	version := spec.Version
+	if len(version) == 0 {
+		version = "$Default"
+	}
+	input := &awsec2.DescribeLaunchTemplateVersionsInput{
+		Versions: aws.StringSlice([]string{version}),
+	}
+	if len(spec.ID) > 0 {
+		input.LaunchTemplateId = aws.String(spec.ID)
+	} else {
+		input.LaunchTemplateName = aws.String(spec.Name)
+	}
+
+	output, err := a.svc.DescribeLaunchTemplateVersions(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.LaunchTemplateVersions) == 0 {
+		return nil, fmt.Errorf("Version %s of launch template %s%s not found", version, spec.ID, spec.Name)
+	}
+
+	templateVersion := output.LaunchTemplateVersions[0]
+	return &ec2v1alpha1.LaunchTemplateStatus{
+		ID:      aws.StringValue(templateVersion.LaunchTemplateId),
+		Version: strconv.FormatInt(aws.Int64Value(templateVersion.VersionNumber), 10),
+	}, nil
+}
+
+// launchTemplateStatus reads the launch template an instance was launched from out of
+// the tags EC2 adds to it
+func launchTemplateStatus(ec2Instance *awsec2.Instance) *ec2v1alpha1.LaunchTemplateStatus {
+	status := &ec2v1alpha1.LaunchTemplateStatus{}
+	for _, tag := range ec2Instance.Tags {
+		switch aws.StringValue(tag.Key) {
+		case launchTemplateIDTag:
+			status.ID = aws.StringValue(tag.Value)
+		case launchTemplateVersionTag:
+			status.Version = aws.StringValue(tag.Value)
+		}
+	}
+	if len(status.ID) == 0 {
+		return nil
+	}
+	return status
+}
@@ -0,0 +1,103 @@
+package ec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// metadataOptionsFailed starts the status message of a failed metadata options update
+const metadataOptionsFailed = "Unable to update metadata options"
+
+// metadataOptionsRequest converts the metadata options of the spec for RunInstances
+func metadataOptionsRequest(options *ec2v1alpha1.MetadataOptions) *awsec2.InstanceMetadataOptionsRequest {
+	request := &awsec2.InstanceMetadataOptionsRequest{}
+	if len(options.HTTPTokens) > 0 {
+		request.HttpTokens = aws.String(options.HTTPTokens)
+	}
+	if options.HTTPPutResponseHopLimit > 0 {
+		request.HttpPutResponseHopLimit = aws.Int64(options.HTTPPutResponseHopLimit)
+	}
+	if len(options.HTTPEndpoint) > 0 {
+		request.HttpEndpoint = aws.String(options.HTTPEndpoint)
+	}
+	if len(options.InstanceMetadataTags) > 0 {
+		request.InstanceMetadataTags = aws.String(options.InstanceMetadataTags)
+	}
+	return request
+}
+
+// metadataOptionsStatus records the metadata options applied to an instance
+func metadataOptionsStatus(options *awsec2.InstanceMetadataOptionsResponse) *ec2v1alpha1.MetadataOptions {
+	if options == nil {
+		return nil
+	}
+	return &ec2v1alpha1.MetadataOptions{
+		HTTPTokens:              aws.StringValue(options.HttpTokens),
+		HTTPPutResponseHopLimit: aws.Int64Value(options.HttpPutResponseHopLimit),
+		HTTPEndpoint:            aws.StringValue(options.HttpEndpoint),
+		InstanceMetadataTags:    aws.StringValue(options.InstanceMetadataTags),
+	}
+}
+
+// MetadataOptionsPending is true when the metadata options applied to the instance do
+// not match the spec
+func MetadataOptionsPending(instance ec2v1alpha1.Instance) bool {
+	desired, applied := instance.Spec.MetadataOptions, instance.Status.MetadataOptions
+	if desired == nil {
+		return false
+	}
+	if applied == nil {
+		return true
+	}
+	return (len(desired.HTTPTokens) > 0 && desired.HTTPTokens != applied.HTTPTokens) ||
+		(desired.HTTPPutResponseHopLimit > 0 && desired.HTTPPutResponseHopLimit != applied.HTTPPutResponseHopLimit) ||
+		(len(desired.HTTPEndpoint) > 0 && desired.HTTPEndpoint != applied.HTTPEndpoint) ||
+		(len(desired.InstanceMetadataTags) > 0 && desired.InstanceMetadataTags != applied.InstanceMetadataTags)
+}
+
+// ReconcileMetadataOptions applies the metadata options of the spec to the instance
+func (a *AWSClient) ReconcileMetadataOptions(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	status = *instance.Status.DeepCopy()
+	request := metadataOptionsRequest(instance.Spec.MetadataOptions)
+	output, err := a.svc.ModifyInstanceMetadataOptions(&awsec2.ModifyInstanceMetadataOptionsInput{
+		InstanceId:              aws.String(instance.Status.InstanceID),
+		HttpTokens:              request.HttpTokens,
+		HttpPutResponseHopLimit: request.HttpPutResponseHopLimit,
+		HttpEndpoint:            request.HttpEndpoint,
+		InstanceMetadataTags:    request.InstanceMetadataTags,
+	})
+	if err != nil {
+		err = fmt.Errorf("%s: %v", metadataOptionsFailed, err)
+		status.Message = err.Error()
+		return status, err
+	}
+
+	// the response can still show the previous options while the change is pending, so
+	// the requested options are recorded to avoid modifying the instance again
+	applied := metadataOptionsStatus(output.InstanceMetadataOptions)
+	if applied == nil {
+		applied = &ec2v1alpha1.MetadataOptions{}
+	}
+	if request.HttpTokens != nil {
+		applied.HTTPTokens = aws.StringValue(request.HttpTokens)
+	}
+	if request.HttpPutResponseHopLimit != nil {
+		applied.HTTPPutResponseHopLimit = aws.Int64Value(request.HttpPutResponseHopLimit)
+	}
+	if request.HttpEndpoint != nil {
+		applied.HTTPEndpoint = aws.StringValue(request.HttpEndpoint)
+	}
+	if request.InstanceMetadataTags != nil {
+		applied.InstanceMetadataTags = aws.StringValue(request.InstanceMetadataTags)
+	}
+	status.MetadataOptions = applied
+	// only clear the message if it was left by a failed metadata options update
+	if strings.HasPrefix(status.Message, metadataOptionsFailed) {
+		status.Message = ""
+	}
+	return status, nil
+}
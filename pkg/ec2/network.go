@@ -0,0 +1,145 @@
+package ec2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// networkInterfaces builds the network interfaces the instance is launched with. The
+// subnet and security groups of the instance are used for interfaces that do not set
+// their own.
+func (a *AWSClient) networkInterfaces(instance ec2v1alpha1.Instance) (result []*awsec2.InstanceNetworkInterfaceSpecification, err error) {
+	seen := make(map[int64]bool)
+	for _, networkInterface := range instance.Spec.NetworkInterfaces {
+		if seen[networkInterface.DeviceIndex] {
+			return nil, fmt.Errorf("Device index %d is used by more than one network interface", networkInterface.DeviceIndex)
+		}
+		seen[networkInterface.DeviceIndex] = true
+
+		subnetID := networkInterface.SubnetID
+		if len(subnetID) == 0 {
+			subnetID = instance.Spec.SubnetID
+		}
+		if len(subnetID) == 0 {
+			return nil, fmt.Errorf("Network interface %d has no subnet", networkInterface.DeviceIndex)
+		}
+
+		groupIDs, groupNames := networkInterface.SecurityGroupIDS, networkInterface.SecurityGroups
+		if len(groupIDs) == 0 && len(groupNames) == 0 {
+			groupIDs, groupNames = instance.Spec.SecurityGroupIDS, instance.Spec.SecurityGroups
+		}
+		securityGroupIDs, err := a.resolveSecurityGroups(subnetID, groupIDs, groupNames)
+		if err != nil {
+			return nil, err
+		}
+
+		specification := &awsec2.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:         aws.Int64(networkInterface.DeviceIndex),
+			SubnetId:            aws.String(subnetID),
+			DeleteOnTermination: aws.Bool(true),
+		}
+		if len(securityGroupIDs) > 0 {
+			specification.Groups = aws.StringSlice(securityGroupIDs)
+		}
+		if len(networkInterface.Description) > 0 {
+			specification.Description = aws.String(networkInterface.Description)
+		}
+		if len(networkInterface.PrivateIPAddress) > 0 {
+			specification.PrivateIpAddress = aws.String(networkInterface.PrivateIPAddress)
+		}
+		if networkInterface.SecondaryPrivateIPAddressCount > 0 {
+			specification.SecondaryPrivateIpAddressCount = aws.Int64(networkInterface.SecondaryPrivateIPAddressCount)
+		}
+		if networkInterface.IPv6AddressCount > 0 {
+			specification.Ipv6AddressCount = aws.Int64(networkInterface.IPv6AddressCount)
+		}
+		result = append(result, specification)
+	}
+
+	return result, nil
+}
+
+// networkInterfaceStatus records the network interfaces attached to the instance
+func networkInterfaceStatus(ec2Instance *awsec2.Instance) (interfaces []ec2v1alpha1.NetworkInterfaceStatus) {
+	for _, networkInterface := range ec2Instance.NetworkInterfaces {
+		status := ec2v1alpha1.NetworkInterfaceStatus{
+			NetworkInterfaceID: aws.StringValue(networkInterface.NetworkInterfaceId),
+			SubnetID:           aws.StringValue(networkInterface.SubnetId),
+			PrivateIP:          aws.StringValue(networkInterface.PrivateIpAddress),
+			SourceDestCheck:    aws.BoolValue(networkInterface.SourceDestCheck),
+		}
+		if networkInterface.Attachment != nil {
+			status.DeviceIndex = aws.Int64Value(networkInterface.Attachment.DeviceIndex)
+		}
+		for _, address := range networkInterface.PrivateIpAddresses {
+			if !aws.BoolValue(address.Primary) {
+				status.SecondaryPrivateIPs = append(status.SecondaryPrivateIPs, aws.StringValue(address.PrivateIpAddress))
+			}
+		}
+		for _, address := range networkInterface.Ipv6Addresses {
+			status.IPv6Addresses = append(status.IPv6Addresses, aws.StringValue(address.Ipv6Address))
+		}
+		interfaces = append(interfaces, status)
+	}
+
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].DeviceIndex < interfaces[j].DeviceIndex
+	})
+	return interfaces
+}
+
+// SourceDestCheckPending is true when the source/dest check of an attached network
+// interface does not match the spec
+func SourceDestCheckPending(instance ec2v1alpha1.Instance) bool {
+	for _, networkInterface := range instance.Spec.NetworkInterfaces {
+		if networkInterface.SourceDestCheck == nil {
+			continue
+		}
+		for _, status := range instance.Status.NetworkInterfaces {
+			if status.DeviceIndex == networkInterface.DeviceIndex && status.SourceDestCheck != *networkInterface.SourceDestCheck {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sourceDestCheckFailed starts the status message of a failed source/dest check update
+const sourceDestCheckFailed = "Unable to update source/dest check"
+
+// ReconcileSourceDestCheck applies the source/dest check from the spec to the attached
+// network interfaces. EC2 does not take the setting at launch.
+func (a *AWSClient) ReconcileSourceDestCheck(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	status = *instance.Status.DeepCopy()
+	for _, networkInterface := range instance.Spec.NetworkInterfaces {
+		if networkInterface.SourceDestCheck == nil {
+			continue
+		}
+		for i, attached := range status.NetworkInterfaces {
+			if attached.DeviceIndex != networkInterface.DeviceIndex || attached.SourceDestCheck == *networkInterface.SourceDestCheck {
+				continue
+			}
+			_, err = a.svc.ModifyNetworkInterfaceAttribute(&awsec2.ModifyNetworkInterfaceAttributeInput{
+				NetworkInterfaceId: aws.String(attached.NetworkInterfaceID),
+				SourceDestCheck:    &awsec2.AttributeBooleanValue{Value: networkInterface.SourceDestCheck},
+			})
+			if err != nil {
+				err = fmt.Errorf("%s of network interface %s: %v", sourceDestCheckFailed, attached.NetworkInterfaceID, err)
+				status.Message = err.Error()
+				return status, err
+			}
+			status.NetworkInterfaces[i].SourceDestCheck = *networkInterface.SourceDestCheck
+		}
+	}
+
+	// only clear the message if it was left by a failed source/dest check update
+	if strings.HasPrefix(status.Message, sourceDestCheckFailed) {
+		status.Message = ""
+	}
+	return status, nil
+}
@@ -0,0 +1,91 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// placement builds the placement the instance is launched with. The availability zone
+// is checked against the subnets of the instance, as EC2 only reports the mismatch as
+// a generic invalid parameter error.
+func (a *AWSClient) placement(instance ec2v1alpha1.Instance) (*awsec2.Placement, error) {
+	spec := instance.Spec.Placement
+	if len(spec.HostID) > 0 && len(spec.Tenancy) > 0 && spec.Tenancy != awsec2.TenancyHost {
+		return nil, fmt.Errorf("placement hostID requires host tenancy, not %s", spec.Tenancy)
+	}
+	if spec.PartitionNumber > 0 && len(spec.GroupName) == 0 {
+		return nil, fmt.Errorf("placement partitionNumber requires a groupName")
+	}
+
+	if len(spec.AvailabilityZone) > 0 {
+		for _, subnetID := range instanceSubnets(instance) {
+			zone, err := a.subnetAvailabilityZone(subnetID)
+			if err != nil {
+				return nil, err
+			}
+			if zone != spec.AvailabilityZone {
+				return nil, fmt.Errorf("Subnet %s is in availability zone %s, not %s", subnetID, zone, spec.AvailabilityZone)
+			}
+		}
+	}
+
+	placement := &awsec2.Placement{}
+	if len(spec.AvailabilityZone) > 0 {
+		placement.AvailabilityZone = aws.String(spec.AvailabilityZone)
+	}
+	if len(spec.GroupName) > 0 {
+		placement.GroupName = aws.String(spec.GroupName)
+	}
+	if spec.PartitionNumber > 0 {
+		placement.PartitionNumber = aws.Int64(spec.PartitionNumber)
+	}
+	if len(spec.Tenancy) > 0 {
+		placement.Tenancy = aws.String(spec.Tenancy)
+	}
+	if len(spec.HostID) > 0 {
+		placement.HostId = aws.String(spec.HostID)
+		placement.Tenancy = aws.String(awsec2.TenancyHost)
+	}
+	if len(spec.Affinity) > 0 {
+		placement.Affinity = aws.String(spec.Affinity)
+	}
+	return placement, nil
+}
+
+// instanceSubnets returns the subnets the instance is launched in
+func instanceSubnets(instance ec2v1alpha1.Instance) (subnets []string) {
+	if len(instance.Spec.NetworkInterfaces) == 0 {
+		if len(instance.Spec.SubnetID) > 0 {
+			subnets = append(subnets, instance.Spec.SubnetID)
+		}
+		return subnets
+	}
+
+	for _, networkInterface := range instance.Spec.NetworkInterfaces {
+		subnetID := networkInterface.SubnetID
+		if len(subnetID) == 0 {
+			subnetID = instance.Spec.SubnetID
+		}
+		if len(subnetID) > 0 && !containsString(subnets, subnetID) {
+			subnets = append(subnets, subnetID)
+		}
+	}
+	return subnets
+}
+
+// subnetAvailabilityZone returns the availability zone of the subnet
+func (a *AWSClient) subnetAvailabilityZone(subnetID string) (string, error) {
+	output, err := a.svc.DescribeSubnets(&awsec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice([]string{subnetID}),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.Subnets) == 0 {
+		return "", fmt.Errorf("Subnet %s not found", subnetID)
+	}
+	return aws.StringValue(output.Subnets[0].AvailabilityZone), nil
+}
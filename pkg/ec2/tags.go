@@ -0,0 +1,200 @@
+package ec2
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Tags applied at launch to identify the object owning an instance
+const (
+	OwnerUIDTag = "ec2.cattle.io/owner-uid"
+	OwnerTag    = "ec2.cattle.io/owner"
+)
+
+// ReconcileInstanceTags adds, updates and removes tags on a provisioned instance to
+// match the spec. Only tags previously applied by the operator are removed.
+func (a *AWSClient) ReconcileInstanceTags(instance ec2v1alpha1.Instance) (status ec2v1alpha1.InstanceStatus, err error) {
+	status = *instance.Status.DeepCopy()
+	managedTags, err := a.reconcileTags(instance.Status.InstanceID, instanceTags(instance), instance.Status.ManagedTags)
+	if err != nil {
+		status.Message = err.Error()
+	} else {
+		status.ManagedTags = managedTags
+		// only clear the message if it was left by a failed tag update
+		if previous := ec2v1alpha1.FindCondition(status.Conditions, ec2v1alpha1.ConditionTagsSynced); previous != nil && previous.Status == corev1.ConditionFalse {
+			status.Message = ""
+		}
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, tagsSyncedCondition(instance.Generation, err))
+	return status, err
+}
+
+// ReconcileKeyPairTags adds, updates and removes tags on an imported keypair to match
+// the spec. Only tags previously applied by the operator are removed.
+func (a *AWSClient) ReconcileKeyPairTags(keypair ec2v1alpha1.ImportKeyPair) (status ec2v1alpha1.ImportKeyPairStatus, err error) {
+	status = *keypair.Status.DeepCopy()
+	managedTags, err := a.reconcileTags(keypair.Status.KeyPairID, keyPairTags(keypair), keypair.Status.ManagedTags)
+	if err != nil {
+		status.Message = err.Error()
+	} else {
+		status.ManagedTags = managedTags
+		status.Message = ""
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, tagsSyncedCondition(keypair.Generation, err))
+	return status, err
+}
+
+// ReconcileVolumeTags adds, updates and removes tags on a volume to match the spec.
+// Only tags previously applied by the operator are removed.
+func (a *AWSClient) ReconcileVolumeTags(volume ec2v1alpha1.Volume) (status ec2v1alpha1.VolumeStatus, err error) {
+	status = *volume.Status.DeepCopy()
+	managedTags, err := a.reconcileTags(volume.Status.VolumeID, volumeTags(volume), volume.Status.ManagedTags)
+	if err != nil {
+		status.Message = err.Error()
+	} else {
+		status.ManagedTags = managedTags
+		if previous := ec2v1alpha1.FindCondition(status.Conditions, ec2v1alpha1.ConditionTagsSynced); previous != nil && previous.Status == corev1.ConditionFalse {
+			status.Message = ""
+		}
+	}
+	ec2v1alpha1.SetCondition(&status.Conditions, tagsSyncedCondition(volume.Generation, err))
+	return status, err
+}
+
+// reconcileTags brings the tags on a resource in line with the desired tags. Keys in
+// managed that are no longer desired are deleted, any other tags on the resource are
+// left untouched. The keys of the desired tags are returned as the new managed set.
+func (a *AWSClient) reconcileTags(resourceID string, desired []*awsec2.Tag, managed []string) (managedTags []string, err error) {
+	output, err := a.svc.DescribeTags(&awsec2.DescribeTagsInput{
+		Filters: []*awsec2.Filter{
+			{Name: aws.String("resource-id"), Values: aws.StringSlice([]string{resourceID})},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	liveTags := make(map[string]string)
+	for _, tag := range output.Tags {
+		liveTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	createTags := []*awsec2.Tag{}
+	desiredKeys := make(map[string]bool)
+	for _, tag := range desired {
+		key := aws.StringValue(tag.Key)
+		desiredKeys[key] = true
+		if live, ok := liveTags[key]; !ok || live != aws.StringValue(tag.Value) {
+			createTags = append(createTags, tag)
+		}
+	}
+
+	deleteTags := []*awsec2.Tag{}
+	for _, key := range managed {
+		if _, ok := liveTags[key]; ok && !desiredKeys[key] {
+			deleteTags = append(deleteTags, &awsec2.Tag{Key: aws.String(key)})
+		}
+	}
+
+	if len(createTags) > 0 {
+		_, err = a.svc.CreateTags(&awsec2.CreateTagsInput{
+			Resources: aws.StringSlice([]string{resourceID}),
+			Tags:      createTags,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(deleteTags) > 0 {
+		_, err = a.svc.DeleteTags(&awsec2.DeleteTagsInput{
+			Resources: aws.StringSlice([]string{resourceID}),
+			Tags:      deleteTags,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tagKeys(desired), nil
+}
+
+// tagsSyncedCondition reports the outcome of a tag reconcile
+func tagsSyncedCondition(generation int64, err error) ec2v1alpha1.Condition {
+	condition := ec2v1alpha1.Condition{
+		Type:               ec2v1alpha1.ConditionTagsSynced,
+		Status:             corev1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "TagsApplied",
+	}
+	if err != nil {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "TagUpdateFailed"
+		condition.Message = err.Error()
+	}
+	return condition
+}
+
+// instanceTags returns the tags from the spec along with the default Name tag
+func instanceTags(instance ec2v1alpha1.Instance) []*awsec2.Tag {
+	tags := []*awsec2.Tag{}
+
+	for _, tagDetails := range instance.Spec.TagSpecifications {
+		tags = append(tags, &awsec2.Tag{Key: aws.String(tagDetails.Name), Value: aws.String(tagDetails.Value)})
+	}
+	//Default tag
+	tags = append(tags, &awsec2.Tag{Key: aws.String("Name"), Value: aws.String(instance.ObjectMeta.Name)})
+	return tags
+}
+
+// launchTags returns the tags applied at launch: the tags from the spec along with the
+// ownership tags identifying the object
+func launchTags(instance ec2v1alpha1.Instance) []*awsec2.Tag {
+	return append(instanceTags(instance), ownershipTags(&instance)...)
+}
+
+// ownershipTags returns the tags identifying the object that owns an EC2 resource
+func ownershipTags(object metav1.Object) []*awsec2.Tag {
+	return []*awsec2.Tag{
+		{Key: aws.String(OwnerUIDTag), Value: aws.String(string(object.GetUID()))},
+		{Key: aws.String(OwnerTag), Value: aws.String(object.GetNamespace() + "/" + object.GetName())},
+	}
+}
+
+// keyPairTags returns the tags from the spec along with the default Name tag
+func keyPairTags(keypair ec2v1alpha1.ImportKeyPair) []*awsec2.Tag {
+	tags := []*awsec2.Tag{}
+
+	for _, tagDetails := range keypair.Spec.TagSpecifications {
+		tags = append(tags, &awsec2.Tag{Key: aws.String(tagDetails.Name), Value: aws.String(tagDetails.Value)})
+	}
+	//Default tag
+	tags = append(tags, &awsec2.Tag{Key: aws.String("Name"), Value: aws.String(keypair.Name)})
+	return tags
+}
+
+// volumeTags returns the tags from the spec along with the default Name tag
+func volumeTags(volume ec2v1alpha1.Volume) []*awsec2.Tag {
+	tags := []*awsec2.Tag{}
+
+	for _, tagDetails := range volume.Spec.TagSpecifications {
+		tags = append(tags, &awsec2.Tag{Key: aws.String(tagDetails.Name), Value: aws.String(tagDetails.Value)})
+	}
+	//Default tag
+	tags = append(tags, &awsec2.Tag{Key: aws.String("Name"), Value: aws.String(volume.Name)})
+	return tags
+}
+
+// tagKeys returns the sorted keys of the tags
+func tagKeys(tags []*awsec2.Tag) (keys []string) {
+	for _, tag := range tags {
+		keys = append(keys, aws.StringValue(tag.Key))
+	}
+	sort.Strings(keys)
+	return keys
+}
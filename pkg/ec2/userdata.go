@@ -0,0 +1,74 @@
+package ec2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"text/template"
+)
+
+// MaxUserDataSize is the limit EC2 puts on user data before it is base64 encoded
+const MaxUserDataSize = 16 * 1024
+
+// EncodeUserData prepares raw user data for RunInstances. The data is optionally
+// compressed with gzip, which cloud-init detects and decompresses, checked against
+// the EC2 size limit and base64 encoded.
+func EncodeUserData(data []byte, compress bool) (string, error) {
+	if compress {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return "", err
+		}
+		if err := writer.Close(); err != nil {
+			return "", err
+		}
+		data = buf.Bytes()
+	}
+
+	if len(data) > MaxUserDataSize {
+		return "", fmt.Errorf("User data is %d bytes, exceeding the limit of %d bytes", len(data), MaxUserDataSize)
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// UserDataContext holds the values available to user data templates
+type UserDataContext struct {
+	Name         string
+	Namespace    string
+	Labels       map[string]string
+	Annotations  map[string]string
+	Region       string
+	SubnetID     string
+	InstanceType string
+	// Secrets maps secret names to their decoded values
+	Secrets map[string]map[string]string
+	// Cluster holds the cluster join information
+	Cluster map[string]string
+}
+
+// RenderUserDataTemplate renders the user data as a Go template. Referencing a value
+// that does not exist is an error, so a machine is never bootstrapped with empty values.
+func RenderUserDataTemplate(data []byte, context UserDataContext) ([]byte, error) {
+	tmpl, err := template.New("userdata").Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse user data template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, context); err != nil {
+		return nil, fmt.Errorf("Unable to render user data template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UserDataHash identifies the encoded user data an instance was launched with
+func UserDataHash(userData string) string {
+	if len(userData) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(userData)))
+}
@@ -0,0 +1,60 @@
+package ec2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderUserDataTemplate(t *testing.T) {
+	context := UserDataContext{
+		Name:      "worker-1",
+		Namespace: "default",
+		Labels:    map[string]string{"role": "worker"},
+		Region:    "ap-southeast-2",
+		Secrets:   map[string]map[string]string{"registry": {"password": "s3cret"}},
+		Cluster:   map[string]string{"server": "https://10.0.0.1:6443", "token": "abc"},
+	}
+	data := []byte(`#!/bin/sh
+hostname {{ .Name }}.{{ .Namespace }}
+echo {{ .Labels.role }} {{ .Region }}
+login {{ index .Secrets "registry" "password" }}
+join {{ .Cluster.server }} {{ .Cluster.token }}
+`)
+
+	rendered, err := RenderUserDataTemplate(data, context)
+	if err != nil {
+		t.Fatalf("RenderUserDataTemplate returned an error: %v", err)
+	}
+	expected := `#!/bin/sh
+hostname worker-1.default
+echo worker ap-southeast-2
+login s3cret
+join https://10.0.0.1:6443 abc
+`
+	if string(rendered) != expected {
+		t.Errorf("unexpected rendered user data:\n%s", rendered)
+	}
+}
+
+func TestRenderUserDataTemplateErrors(t *testing.T) {
+	context := UserDataContext{Name: "worker-1"}
+	for name, data := range map[string]string{
+		"parse error":   "{{ .Name ",
+		"missing field": "{{ .Zone }}",
+		"missing key":   "{{ .Cluster.token }}",
+	} {
+		if _, err := RenderUserDataTemplate([]byte(data), context); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEncodeUserDataSizeLimit(t *testing.T) {
+	data := []byte(strings.Repeat("a", MaxUserDataSize+1))
+	if _, err := EncodeUserData(data, false); err == nil {
+		t.Errorf("expected user data over the limit to be rejected")
+	}
+	if _, err := EncodeUserData(data, true); err != nil {
+		t.Errorf("expected compressed user data to fit the limit: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package ec2
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awsec2 "github.com/aws/aws-sdk-go/service/ec2"
+	ec2v1alpha1 "github.com/ibrokethecloud/ec2-operator/pkg/api/v1alpha1"
+)
+
+// volumeNotFound is the error code returned for volume ids that no longer exist
+const volumeNotFound = "InvalidVolume.NotFound"
+
+// volumeShrink is the error code returned by ModifyVolume for a size below the current size
+const volumeShrink = "VolumeShrink"
+
+// CreateVolume creates the EBS volume in the availability zone. CreateVolume does not
+// take a client token, so a volume already created for the object is looked up by its
+// ownership tag first.
+func (a *AWSClient) CreateVolume(volume ec2v1alpha1.Volume, availabilityZone string) (status ec2v1alpha1.VolumeStatus, err error) {
+	output, err := a.svc.DescribeVolumes(&awsec2.DescribeVolumesInput{
+		Filters: []*awsec2.Filter{
+			{Name: aws.String("tag:" + OwnerUIDTag), Values: aws.StringSlice([]string{string(volume.UID)})},
+			{Name: aws.String("status"), Values: aws.StringSlice([]string{awsec2.VolumeStateCreating, awsec2.VolumeStateAvailable, awsec2.VolumeStateInUse})},
+		},
+	})
+	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+	if len(output.Volumes) > 0 {
+		return volumeStatus(volume.Status, output.Volumes[0]), nil
+	}
+
+	if len(availabilityZone) == 0 {
+		err = fmt.Errorf("availabilityZone is required for a volume that is not attached to an instance")
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+	if volume.Spec.Size == 0 && len(volume.Spec.SnapshotID) == 0 {
+		err = fmt.Errorf("size or snapshotID is required to create a volume")
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+
+	input := &awsec2.CreateVolumeInput{
+		AvailabilityZone: aws.String(availabilityZone),
+		Encrypted:        aws.Bool(volume.Spec.Encrypted),
+		TagSpecifications: []*awsec2.TagSpecification{
+			{
+				ResourceType: aws.String(awsec2.ResourceTypeVolume),
+				Tags:         append(volumeTags(volume), ownershipTags(&volume)...),
+			},
+		},
+	}
+	if volume.Spec.Size > 0 {
+		input.Size = aws.Int64(volume.Spec.Size)
+	}
+	if len(volume.Spec.VolumeType) > 0 {
+		input.VolumeType = aws.String(volume.Spec.VolumeType)
+	}
+	if volume.Spec.IOPS > 0 {
+		input.Iops = aws.Int64(volume.Spec.IOPS)
+	}
+	if len(volume.Spec.KMSKeyID) > 0 {
+		input.KmsKeyId = aws.String(volume.Spec.KMSKeyID)
+	}
+	if len(volume.Spec.SnapshotID) > 0 {
+		input.SnapshotId = aws.String(volume.Spec.SnapshotID)
+	}
+
+	created, err := a.svc.CreateVolume(input)
+	if err != nil {
+		status.Status = Error
+		status.Message = err.Error()
+		return status, err
+	}
+
+	status = volumeStatus(volume.Status, created)
+	status.ManagedTags = tagKeys(volumeTags(volume))
+	status.Message = ""
+	ec2v1alpha1.SetCondition(&status.Conditions, tagsSyncedCondition(volume.Generation, nil))
+	return status, nil
+}
+
+// RefreshVolume updates the status from the live volume and its latest modification.
+// The message is cleared, as the reconcile following the refresh reports any error again.
+func (a *AWSClient) RefreshVolume(volume ec2v1alpha1.Volume) (status ec2v1alpha1.VolumeStatus, err error) {
+	status = *volume.Status.DeepCopy()
+	status.Message = ""
+	output, err := a.svc.DescribeVolumes(&awsec2.DescribeVolumesInput{
+		VolumeIds: aws.StringSlice([]string{volume.Status.VolumeID}),
+	})
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+	if len(output.Volumes) == 0 {
+		err = awserr.New(volumeNotFound, fmt.Sprintf("Volume %s not found", volume.Status.VolumeID), nil)
+		status.Message = err.Error()
+		return status, err
+	}
+
+	modifications, err := a.svc.DescribeVolumesModifications(&awsec2.DescribeVolumesModificationsInput{
+		VolumeIds: aws.StringSlice([]string{volume.Status.VolumeID}),
+	})
+	// volumes that were never modified are reported as not found
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidVolumeModification.NotFound" {
+		err = nil
+	}
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+	status.Modification = nil
+	if modifications != nil && len(modifications.VolumesModifications) > 0 {
+		latest := modifications.VolumesModifications[0]
+		for _, modification := range modifications.VolumesModifications[1:] {
+			if aws.TimeValue(modification.StartTime).After(aws.TimeValue(latest.StartTime)) {
+				latest = modification
+			}
+		}
+		status.Modification = &ec2v1alpha1.VolumeModificationStatus{
+			State:      aws.StringValue(latest.ModificationState),
+			Progress:   aws.Int64Value(latest.Progress),
+			TargetSize: aws.Int64Value(latest.TargetSize),
+			TargetType: aws.StringValue(latest.TargetVolumeType),
+			TargetIOPS: aws.Int64Value(latest.TargetIops),
+			Message:    aws.StringValue(latest.StatusMessage),
+		}
+	}
+
+	return volumeStatus(status, output.Volumes[0]), nil
+}
+
+// volumeStatus records the live volume in the status and derives the status phase
+func volumeStatus(status ec2v1alpha1.VolumeStatus, ebsVolume *awsec2.Volume) ec2v1alpha1.VolumeStatus {
+	status.VolumeID = aws.StringValue(ebsVolume.VolumeId)
+	status.VolumeState = aws.StringValue(ebsVolume.State)
+	status.AvailabilityZone = aws.StringValue(ebsVolume.AvailabilityZone)
+	status.Size = aws.Int64Value(ebsVolume.Size)
+	status.VolumeType = aws.StringValue(ebsVolume.VolumeType)
+	status.IOPS = aws.Int64Value(ebsVolume.Iops)
+
+	status.AttachedInstanceID, status.DeviceName, status.AttachmentState = "", "", ""
+	for _, attachment := range ebsVolume.Attachments {
+		status.AttachedInstanceID = aws.StringValue(attachment.InstanceId)
+		status.DeviceName = aws.StringValue(attachment.Device)
+		status.AttachmentState = aws.StringValue(attachment.State)
+	}
+
+	switch {
+	case status.VolumeState == awsec2.VolumeStateCreating:
+		status.Status = Creating
+	case status.AttachmentState == awsec2.VolumeAttachmentStateAttaching:
+		status.Status = Attaching
+	case status.AttachmentState == awsec2.VolumeAttachmentStateDetaching:
+		status.Status = Detaching
+	case VolumeModifying(status):
+		status.Status = Resizing
+	default:
+		status.Status = Provisioned
+	}
+	return status
+}
+
+// VolumeModifying is true while a modification of the volume is in progress. The new
+// size can be used once the modification is optimizing.
+func VolumeModifying(status ec2v1alpha1.VolumeStatus) bool {
+	return status.Modification != nil && status.Modification.State == awsec2.VolumeModificationStateModifying
+}
+
+// ModifyVolume changes the size, type or iops of the volume to match the spec. Volumes
+// can only grow.
+func (a *AWSClient) ModifyVolume(volume ec2v1alpha1.Volume) (status ec2v1alpha1.VolumeStatus, err error) {
+	status = *volume.Status.DeepCopy()
+	if volume.Spec.Size > 0 && volume.Spec.Size < volume.Status.Size {
+		err = awserr.New(volumeShrink, fmt.Sprintf("Volume can not be shrunk from %dGiB to %dGiB", volume.Status.Size, volume.Spec.Size), nil)
+		status.Message = err.Error()
+		return status, err
+	}
+
+	input := &awsec2.ModifyVolumeInput{VolumeId: aws.String(volume.Status.VolumeID)}
+	if volume.Spec.Size > volume.Status.Size {
+		input.Size = aws.Int64(volume.Spec.Size)
+	}
+	if len(volume.Spec.VolumeType) > 0 && volume.Spec.VolumeType != volume.Status.VolumeType {
+		input.VolumeType = aws.String(volume.Spec.VolumeType)
+	}
+	if volume.Spec.IOPS > 0 && volume.Spec.IOPS != volume.Status.IOPS {
+		input.Iops = aws.Int64(volume.Spec.IOPS)
+	}
+
+	output, err := a.svc.ModifyVolume(input)
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+
+	modification := output.VolumeModification
+	status.Modification = &ec2v1alpha1.VolumeModificationStatus{
+		State:      aws.StringValue(modification.ModificationState),
+		Progress:   aws.Int64Value(modification.Progress),
+		TargetSize: aws.Int64Value(modification.TargetSize),
+		TargetType: aws.StringValue(modification.TargetVolumeType),
+		TargetIOPS: aws.Int64Value(modification.TargetIops),
+	}
+	status.Status = Resizing
+	status.Message = ""
+	return status, nil
+}
+
+// IsModificationRejected is true for errors of ModifyVolume that retrying does not
+// resolve, such as shrinking the volume, setting iops on a gp2 volume or modifying the
+// volume again within six hours of its last modification
+func IsModificationRejected(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == volumeShrink {
+		return true
+	}
+	requestErr, ok := err.(awserr.RequestFailure)
+	return ok && requestErr.StatusCode() == http.StatusBadRequest && !request.IsErrorThrottle(err) && !request.IsErrorRetryable(err)
+}
+
+// VolumeModificationFailed is true when the latest modification of the volume failed
+// while targeting the size, type and iops of the spec
+func VolumeModificationFailed(spec ec2v1alpha1.VolumeSpec, status ec2v1alpha1.VolumeStatus) bool {
+	modification := status.Modification
+	return modification != nil && modification.State == awsec2.VolumeModificationStateFailed &&
+		(spec.Size == 0 || spec.Size == modification.TargetSize) &&
+		(len(spec.VolumeType) == 0 || spec.VolumeType == modification.TargetType) &&
+		(spec.IOPS == 0 || spec.IOPS == modification.TargetIOPS)
+}
+
+// VolumeModificationPending is true when the size, type or iops of the volume do not
+// match the spec
+func VolumeModificationPending(volume ec2v1alpha1.Volume) bool {
+	spec, status := volume.Spec, volume.Status
+	return (spec.Size > 0 && spec.Size != status.Size) ||
+		(len(spec.VolumeType) > 0 && spec.VolumeType != status.VolumeType) ||
+		(spec.IOPS > 0 && spec.IOPS != status.IOPS)
+}
+
+// AttachVolume attaches the volume to the instance under the device name of the spec
+func (a *AWSClient) AttachVolume(volume ec2v1alpha1.Volume, instanceID string) (status ec2v1alpha1.VolumeStatus, err error) {
+	status = *volume.Status.DeepCopy()
+	output, err := a.svc.AttachVolume(&awsec2.AttachVolumeInput{
+		VolumeId:   aws.String(volume.Status.VolumeID),
+		InstanceId: aws.String(instanceID),
+		Device:     aws.String(volume.Spec.Attachment.DeviceName),
+	})
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+
+	status.AttachedInstanceID = instanceID
+	status.DeviceName = aws.StringValue(output.Device)
+	status.AttachmentState = aws.StringValue(output.State)
+	status.Status = Attaching
+	status.Message = ""
+	return status, nil
+}
+
+// DetachVolume detaches the volume from the instance it is attached to
+func (a *AWSClient) DetachVolume(volume ec2v1alpha1.Volume) (status ec2v1alpha1.VolumeStatus, err error) {
+	status = *volume.Status.DeepCopy()
+	output, err := a.svc.DetachVolume(&awsec2.DetachVolumeInput{
+		VolumeId:   aws.String(volume.Status.VolumeID),
+		InstanceId: aws.String(volume.Status.AttachedInstanceID),
+	})
+	if err != nil {
+		status.Message = err.Error()
+		return status, err
+	}
+
+	status.AttachmentState = aws.StringValue(output.State)
+	status.Status = Detaching
+	status.Message = ""
+	return status, nil
+}
+
+// DeleteVolume deletes the volume, a volume that no longer exists is not an error
+func (a *AWSClient) DeleteVolume(volume ec2v1alpha1.Volume) (err error) {
+	_, err = a.svc.DeleteVolume(&awsec2.DeleteVolumeInput{
+		VolumeId: aws.String(volume.Status.VolumeID),
+	})
+	if IsVolumeNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// IsVolumeNotFound is true for errors returned for volumes that no longer exist
+func IsVolumeNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == volumeNotFound
+}